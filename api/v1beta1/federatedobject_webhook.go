@@ -0,0 +1,55 @@
+package v1beta1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var federatedobjectlog = logf.Log.WithName("federatedobject-resource")
+
+const federatedObjectValidatePath = "/validate-waofed-bitmedia-co-jp-v1beta1-federatedobject"
+
+// NOTE: failurePolicy is ignore so that WAOFed never blocks KubeFed users; the controllers validate the annotations again.
+//+kubebuilder:webhook:path=/validate-waofed-bitmedia-co-jp-v1beta1-federatedobject,mutating=false,failurePolicy=ignore,sideEffects=None,groups=types.kubefed.io,resources=federateddeployments;federatedreplicasets;federatedservices,verbs=create;update,versions=v1beta1,name=vfederatedobject.kb.io,admissionReviewVersions=v1
+
+// FederatedObjectValidator validates the optimizer override annotations of federated objects.
+type FederatedObjectValidator struct{}
+
+var _ admission.Handler = &FederatedObjectValidator{}
+
+// SetupFederatedObjectWebhookWithManager registers FederatedObjectValidator to the webhook server.
+func SetupFederatedObjectWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(federatedObjectValidatePath, &webhook.Admission{Handler: &FederatedObjectValidator{}})
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (v *FederatedObjectValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	federatedobjectlog.Info("validate", "kind", req.Kind.Kind, "namespace", obj.Namespace, "name", obj.Name)
+
+	if err := validateFederatedObjectAnnotations(req.Kind.Kind, obj.Annotations); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+func validateFederatedObjectAnnotations(kind string, annotations map[string]string) error {
+	switch kind {
+	case "FederatedService":
+		return ValidateSLPOptimizerOverrides(annotations)
+	default: // FederatedDeployment, FederatedReplicaSet
+		return ValidateRSPOptimizerOverrides(annotations)
+	}
+}
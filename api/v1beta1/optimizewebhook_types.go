@@ -0,0 +1,76 @@
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The protocol between WAOFed and external optimizers called with method "webhook".
+//
+// WAOFed POSTs an OptimizeRequest in JSON to spec.{scheduling,loadbalancing}.optimizer.webhook.url,
+// and the external optimizer responds with an OptimizeResponse in JSON with status 200.
+// Both have apiVersion "waofed.bitmedia.co.jp/v1beta1", so that the protocol can be versioned with the API.
+const (
+	OptimizeRequestKind  = "OptimizeRequest"
+	OptimizeResponseKind = "OptimizeResponse"
+)
+
+// OptimizeType represents which optimizer sends an OptimizeRequest.
+type OptimizeType string
+
+const (
+	// OptimizeTypeScheduling is sent by RSPOptimizer to compute a ReplicaSchedulingPreference.
+	OptimizeTypeScheduling OptimizeType = "scheduling"
+	// OptimizeTypeLoadBalancing is sent by SLPOptimizer to compute a ServiceLoadbalancingPreference.
+	OptimizeTypeLoadBalancing OptimizeType = "loadbalancing"
+)
+
+// OptimizeRequest is the request sent to an external optimizer.
+type OptimizeRequest struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Type specifies which optimizer sends the request.
+	Type OptimizeType `json:"type"`
+
+	// Clusters lists the candidate clusters.
+	// The external optimizer should only return clusters in the list.
+	Clusters []string `json:"clusters"`
+
+	// Object is the federated object to optimize (e.g. FederatedDeployment, FederatedService).
+	Object runtime.RawExtension `json:"object"`
+
+	// Replicas is the number of replicas to distribute.
+	// For "loadbalancing", it is the total replicas of the backend FederatedDeployments.
+	Replicas int32 `json:"replicas"`
+
+	// Requests is the resource requests of a replica.
+	// For "loadbalancing", it is averaged over all replicas of the backend FederatedDeployments.
+	// +optional
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+}
+
+// OptimizeResponse is the response from an external optimizer.
+type OptimizeResponse struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Clusters maps between cluster names and their preferences.
+	// Clusters not listed get no replicas (or no access for "loadbalancing").
+	Clusters map[string]OptimizeClusterPreferences `json:"clusters"`
+}
+
+// OptimizeClusterPreferences represents the preferences of a cluster returned by an external optimizer.
+type OptimizeClusterPreferences struct {
+	// Weight is the weight of the cluster.
+	Weight int64 `json:"weight"`
+
+	// MinReplicas is the minimum number of replicas scheduled on the cluster.
+	// Ignored for "loadbalancing". (default: 0)
+	// +optional
+	MinReplicas int64 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas scheduled on the cluster.
+	// Ignored for "loadbalancing". (default: unbounded)
+	// +optional
+	MaxReplicas *int64 `json:"maxReplicas,omitempty"`
+}
@@ -0,0 +1,153 @@
+package v1beta1
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotations on a federated object that override the optimizer settings in WAOFedConfig for the object.
+const (
+	// RSPOptimizerMethodAnnotation overrides spec.scheduling.optimizer.method (e.g. "wao").
+	RSPOptimizerMethodAnnotation = "waofed.bitmedia.co.jp/scheduling-method"
+	// RSPOptimizerReoptimizeIntervalAnnotation overrides spec.scheduling.optimizer.reoptimizeInterval (e.g. "10m").
+	RSPOptimizerReoptimizeIntervalAnnotation = "waofed.bitmedia.co.jp/scheduling-reoptimize-interval"
+	// RSPRebalanceAnnotation overrides spec.scheduling.optimizer.rebalance (e.g. "false").
+	RSPRebalanceAnnotation = "waofed.bitmedia.co.jp/scheduling-rebalance"
+	// RSPIntersectWithClusterSelectorAnnotation overrides spec.scheduling.optimizer.intersectWithClusterSelector (e.g. "false").
+	RSPIntersectWithClusterSelectorAnnotation = "waofed.bitmedia.co.jp/scheduling-intersect-with-cluster-selector"
+	// SLPOptimizerMethodAnnotation overrides spec.loadbalancing.optimizer.method (e.g. "wao").
+	SLPOptimizerMethodAnnotation = "waofed.bitmedia.co.jp/loadbalancing-method"
+	// SLPOptimizerReoptimizeIntervalAnnotation overrides spec.loadbalancing.optimizer.reoptimizeInterval (e.g. "10m").
+	SLPOptimizerReoptimizeIntervalAnnotation = "waofed.bitmedia.co.jp/loadbalancing-reoptimize-interval"
+)
+
+var (
+	rspOptimizerMethods = map[RSPOptimizerMethod]struct{}{
+		RSPOptimizerMethodRoundRobin: {},
+		RSPOptimizerMethodWAO:        {},
+		RSPOptimizerMethodCapacity:   {},
+		RSPOptimizerMethodCarbon:     {},
+		RSPOptimizerMethodPrice:      {},
+		RSPOptimizerMethodWebhook:    {},
+	}
+	slpOptimizerMethods = map[SLPOptimizerMethod]struct{}{
+		SLPOptimizerMethodRoundRobin: {},
+		SLPOptimizerMethodWAO:        {},
+		SLPOptimizerMethodWebhook:    {},
+	}
+)
+
+// ValidateRSPOptimizerOverrides validates the RSPOptimizer override annotations of a federated object.
+func ValidateRSPOptimizerOverrides(annotations map[string]string) error {
+	if v, ok := annotations[RSPOptimizerMethodAnnotation]; ok {
+		if _, ok := rspOptimizerMethods[RSPOptimizerMethod(v)]; !ok {
+			return fmt.Errorf("invalid annotation %s: unknown method %s", RSPOptimizerMethodAnnotation, v)
+		}
+	}
+	if _, err := parseReoptimizeIntervalAnnotation(annotations, RSPOptimizerReoptimizeIntervalAnnotation); err != nil {
+		return err
+	}
+	for _, key := range []string{RSPRebalanceAnnotation, RSPIntersectWithClusterSelectorAnnotation} {
+		if _, err := parseBoolAnnotation(annotations, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSLPOptimizerOverrides validates the SLPOptimizer override annotations of a federated object.
+func ValidateSLPOptimizerOverrides(annotations map[string]string) error {
+	if v, ok := annotations[SLPOptimizerMethodAnnotation]; ok {
+		if _, ok := slpOptimizerMethods[SLPOptimizerMethod(v)]; !ok {
+			return fmt.Errorf("invalid annotation %s: unknown method %s", SLPOptimizerMethodAnnotation, v)
+		}
+	}
+	if _, err := parseReoptimizeIntervalAnnotation(annotations, SLPOptimizerReoptimizeIntervalAnnotation); err != nil {
+		return err
+	}
+	return nil
+}
+
+func parseReoptimizeIntervalAnnotation(annotations map[string]string, key string) (*metav1.Duration, error) {
+	v, ok := annotations[key]
+	if !ok {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %w", key, err)
+	}
+	if d < 0 {
+		return nil, fmt.Errorf("invalid annotation %s: must not be negative", key)
+	}
+	return &metav1.Duration{Duration: d}, nil
+}
+
+func parseBoolAnnotation(annotations map[string]string, key string) (*bool, error) {
+	v, ok := annotations[key]
+	if !ok {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %w", key, err)
+	}
+	return &b, nil
+}
+
+// WithOverrides returns a copy of the settings merged with the override annotations of a federated object.
+// The settings are expected to be defaulted by the webhook.
+func (s *RSPOptimizerSettings) WithOverrides(annotations map[string]string) (*RSPOptimizerSettings, error) {
+	if err := ValidateRSPOptimizerOverrides(annotations); err != nil {
+		return nil, err
+	}
+	out := s.DeepCopy()
+	if v, ok := annotations[RSPOptimizerMethodAnnotation]; ok {
+		m := RSPOptimizerMethod(v)
+		out.Method = &m
+	}
+	if d, _ := parseReoptimizeIntervalAnnotation(annotations, RSPOptimizerReoptimizeIntervalAnnotation); d != nil {
+		out.ReoptimizeInterval = d
+	}
+	if b, _ := parseBoolAnnotation(annotations, RSPRebalanceAnnotation); b != nil {
+		out.Rebalance = b
+	}
+	if b, _ := parseBoolAnnotation(annotations, RSPIntersectWithClusterSelectorAnnotation); b != nil {
+		out.IntersectWithClusterSelector = b
+	}
+	// method specific settings are taken from WAOFedConfig, which the webhook only validates for method and fallbackMethods
+	if _, ok := annotations[RSPOptimizerMethodAnnotation]; ok {
+		defaultRSPOptimizerMethodSettings(out)
+		if err := validateRSPOptimizerMethod(*out.Method, out, "spec.scheduling.optimizer", "method"); err != nil {
+			return nil, fmt.Errorf("annotation %s requires valid settings: %w", RSPOptimizerMethodAnnotation, err)
+		}
+	}
+	return out, nil
+}
+
+// WithOverrides returns a copy of the settings merged with the override annotations of a federated object.
+// The settings are expected to be defaulted by the webhook.
+func (s *SLPOptimizerSettings) WithOverrides(annotations map[string]string) (*SLPOptimizerSettings, error) {
+	if err := ValidateSLPOptimizerOverrides(annotations); err != nil {
+		return nil, err
+	}
+	out := s.DeepCopy()
+	if v, ok := annotations[SLPOptimizerMethodAnnotation]; ok {
+		m := SLPOptimizerMethod(v)
+		out.Method = &m
+	}
+	if d, _ := parseReoptimizeIntervalAnnotation(annotations, SLPOptimizerReoptimizeIntervalAnnotation); d != nil {
+		out.ReoptimizeInterval = d
+	}
+	// method specific settings are taken from WAOFedConfig, which the webhook only validates for method and fallbackMethods
+	if _, ok := annotations[SLPOptimizerMethodAnnotation]; ok {
+		defaultSLPOptimizerMethodSettings(out)
+		if err := validateSLPOptimizerMethod(*out.Method, out, "spec.loadbalancing.optimizer", "method"); err != nil {
+			return nil, fmt.Errorf("annotation %s requires valid settings: %w", SLPOptimizerMethodAnnotation, err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,240 @@
+package v1beta1_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func TestRSPOptimizerSettings_WithOverrides(t *testing.T) {
+	rr := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodRoundRobin)
+	wao := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodWAO)
+	// defaulted by the webhook
+	estimator := &v1beta1.WAOEstimatorSetting{Endpoint: "http://localhost:5657", Namespace: "default", Name: "default",
+		CacheTTL: &metav1.Duration{Duration: 30 * time.Second}, Timeout: &metav1.Duration{Duration: 10 * time.Second}}
+	estimators := map[string]*v1beta1.WAOEstimatorSetting{"c1": estimator}
+	discovery := &v1beta1.WAOEstimatorDiscovery{Mode: v1beta1.WAOEstimatorDiscoveryModeAnnotation,
+		Key: v1beta1.WAOEstimatorEndpointAnnotation, Template: &v1beta1.WAOEstimatorSetting{Namespace: "default", Name: "default",
+			CacheTTL: &metav1.Duration{Duration: 30 * time.Second}, Timeout: &metav1.Duration{Duration: 10 * time.Second}}}
+	tests := []struct {
+		name        string
+		settings    *v1beta1.RSPOptimizerSettings
+		annotations map[string]string
+		want        *v1beta1.RSPOptimizerSettings
+		wantErr     bool
+	}{
+		{"no_overrides",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.DefaultRSPOptimizerAnnotation: ""},
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			false},
+		{"method",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, WAOEstimators: estimators},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"},
+			&v1beta1.RSPOptimizerSettings{Method: &wao, WAOEstimators: estimators},
+			false},
+		{"interval",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, ReoptimizeInterval: &metav1.Duration{Duration: time.Hour}},
+			map[string]string{v1beta1.RSPOptimizerReoptimizeIntervalAnnotation: "10m"},
+			&v1beta1.RSPOptimizerSettings{Method: &rr, ReoptimizeInterval: &metav1.Duration{Duration: 10 * time.Minute}},
+			false},
+		{"interval_zero",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, ReoptimizeInterval: &metav1.Duration{Duration: time.Hour}},
+			map[string]string{v1beta1.RSPOptimizerReoptimizeIntervalAnnotation: "0s"},
+			&v1beta1.RSPOptimizerSettings{Method: &rr, ReoptimizeInterval: &metav1.Duration{Duration: 0}},
+			false},
+		{"rebalance",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, Rebalance: pointer.Bool(true), IntersectWithClusterSelector: pointer.Bool(true)},
+			map[string]string{v1beta1.RSPRebalanceAnnotation: "false", v1beta1.RSPIntersectWithClusterSelectorAnnotation: "false"},
+			&v1beta1.RSPOptimizerSettings{Method: &rr, Rebalance: pointer.Bool(false), IntersectWithClusterSelector: pointer.Bool(false)},
+			false},
+		{"invalid_rebalance",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPRebalanceAnnotation: "no"},
+			nil,
+			true},
+		{"invalid_method",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "foo"},
+			nil,
+			true},
+		{"invalid_interval",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerReoptimizeIntervalAnnotation: "10"},
+			nil,
+			true},
+		{"negative_interval",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerReoptimizeIntervalAnnotation: "-1m"},
+			nil,
+			true},
+		{"wao_without_estimators",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"},
+			nil,
+			true},
+		{"wao_with_discovery",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, WAOEstimatorDiscovery: discovery},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"},
+			&v1beta1.RSPOptimizerSettings{Method: &wao, WAOEstimatorDiscovery: discovery},
+			false},
+		{"wao_not_defaulted",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, WAOEstimators: map[string]*v1beta1.WAOEstimatorSetting{"c1": {Endpoint: "http://localhost:5657"}}},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"},
+			&v1beta1.RSPOptimizerSettings{Method: &wao, WAOEstimators: estimators},
+			false},
+		{"wao_invalid_estimators",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, WAOEstimators: map[string]*v1beta1.WAOEstimatorSetting{"c1": {Endpoint: "localhost"}}},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"},
+			nil,
+			true},
+		{"carbon_without_source",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, CarbonIntensity: &v1beta1.CarbonIntensitySource{}},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "carbon"},
+			nil,
+			true},
+		{"price_without_estimators",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, Tariffs: map[string]v1beta1.TariffSchedule{"c1": {Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "0.25"}}}}},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "price"},
+			nil,
+			true},
+		{"webhook_without_webhook",
+			&v1beta1.RSPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "webhook"},
+			nil,
+			true},
+		{"webhook_invalid_url",
+			&v1beta1.RSPOptimizerSettings{Method: &rr, Webhook: &v1beta1.OptimizerWebhook{URL: "ftp://localhost"}},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "webhook"},
+			nil,
+			true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.settings.DeepCopy()
+			got, err := tt.settings.WithOverrides(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithOverrides() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("WithOverrides() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+			if diff := cmp.Diff(tt.settings, orig); diff != "" {
+				t.Errorf("WithOverrides() modified the receiver, diff %s", diff)
+			}
+		})
+	}
+}
+
+func TestSLPOptimizerSettings_WithOverrides(t *testing.T) {
+	rr := v1beta1.SLPOptimizerMethod(v1beta1.SLPOptimizerMethodRoundRobin)
+	wao := v1beta1.SLPOptimizerMethod(v1beta1.SLPOptimizerMethodWAO)
+	webhook := v1beta1.SLPOptimizerMethod(v1beta1.SLPOptimizerMethodWebhook)
+	// defaulted by the webhook
+	estimators := map[string]*v1beta1.WAOEstimatorSetting{"c1": {Endpoint: "http://localhost:5657", Namespace: "default", Name: "default",
+		CacheTTL: &metav1.Duration{Duration: 30 * time.Second}, Timeout: &metav1.Duration{Duration: 10 * time.Second}}}
+	tests := []struct {
+		name        string
+		settings    *v1beta1.SLPOptimizerSettings
+		annotations map[string]string
+		want        *v1beta1.SLPOptimizerSettings
+		wantErr     bool
+	}{
+		{"no_overrides",
+			&v1beta1.SLPOptimizerSettings{Method: &wao, WAOEstimators: estimators},
+			nil,
+			&v1beta1.SLPOptimizerSettings{Method: &wao, WAOEstimators: estimators},
+			false},
+		{"method_and_interval",
+			&v1beta1.SLPOptimizerSettings{Method: &wao, WAOEstimators: estimators},
+			map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "rr", v1beta1.SLPOptimizerReoptimizeIntervalAnnotation: "1h"},
+			&v1beta1.SLPOptimizerSettings{Method: &rr, WAOEstimators: estimators, ReoptimizeInterval: &metav1.Duration{Duration: time.Hour}},
+			false},
+		{"rsp_annotations_ignored",
+			&v1beta1.SLPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "foo"},
+			&v1beta1.SLPOptimizerSettings{Method: &rr},
+			false},
+		{"invalid_method",
+			&v1beta1.SLPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "foo"},
+			nil,
+			true},
+		{"wao_without_estimators",
+			&v1beta1.SLPOptimizerSettings{Method: &rr},
+			map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "wao"},
+			nil,
+			true},
+		{"webhook",
+			&v1beta1.SLPOptimizerSettings{Method: &rr, Webhook: &v1beta1.OptimizerWebhook{URL: "http://localhost:8080"}},
+			map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "webhook"},
+			&v1beta1.SLPOptimizerSettings{Method: &webhook, Webhook: &v1beta1.OptimizerWebhook{URL: "http://localhost:8080", Timeout: &metav1.Duration{Duration: 10 * time.Second}}},
+			false},
+		{"webhook_invalid_timeout",
+			&v1beta1.SLPOptimizerSettings{Method: &rr, Webhook: &v1beta1.OptimizerWebhook{URL: "http://localhost:8080", Timeout: &metav1.Duration{}}},
+			map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "webhook"},
+			nil,
+			true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.settings.WithOverrides(tt.annotations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithOverrides() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("WithOverrides() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func TestFederatedObjectValidator_Handle(t *testing.T) {
+	helperRequest := func(kind string, annotations map[string]string) admission.Request {
+		obj := &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "types.kubefed.io/v1beta1", Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", Annotations: annotations},
+		}
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Group: "types.kubefed.io", Version: "v1beta1", Kind: kind},
+			Object: runtime.RawExtension{Raw: raw},
+		}}
+	}
+	tests := []struct {
+		name    string
+		req     admission.Request
+		allowed bool
+	}{
+		{"no_annotations", helperRequest("FederatedDeployment", nil), true},
+		{"fdeploy_valid", helperRequest("FederatedDeployment", map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "wao"}), true},
+		{"fdeploy_invalid", helperRequest("FederatedDeployment", map[string]string{v1beta1.RSPOptimizerMethodAnnotation: "foo"}), false},
+		{"frs_invalid", helperRequest("FederatedReplicaSet", map[string]string{v1beta1.RSPOptimizerReoptimizeIntervalAnnotation: "foo"}), false},
+		{"fsvc_valid", helperRequest("FederatedService", map[string]string{v1beta1.SLPOptimizerReoptimizeIntervalAnnotation: "5m"}), true},
+		{"fsvc_invalid", helperRequest("FederatedService", map[string]string{v1beta1.SLPOptimizerMethodAnnotation: "foo"}), false},
+		{"invalid_object", admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Object: runtime.RawExtension{Raw: []byte("{")}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := (&v1beta1.FederatedObjectValidator{}).Handle(context.Background(), tt.req)
+			if resp.Allowed != tt.allowed {
+				t.Errorf("Handle() allowed = %v, want %v, result %v", resp.Allowed, tt.allowed, resp.Result)
+			}
+		})
+	}
+}
@@ -4,6 +4,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// SLPAnnotation is the annotation to specify the ServiceLoadbalancingPreference (in the same namespace)
+// to be applied to the annotated resource (e.g. Gateway API HTTPRoute).
+const SLPAnnotation = "waofed.bitmedia.co.jp/slp"
+
 // ServiceLoadbalancingPreferenceSpec defines the desired state of ServiceLoadbalancingPreference
 type ServiceLoadbalancingPreferenceSpec struct {
 	// Clusters maps between cluster names and preference weight settings in these clusters.
@@ -21,13 +25,54 @@ type ClusterPreferences struct {
 
 // ServiceLoadbalancingPreferenceStatus defines the observed state of ServiceLoadbalancingPreference
 type ServiceLoadbalancingPreferenceStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Optimizer is written by the optimizer that computed spec.clusters.
+	// +optional
+	Optimizer *SLPOptimizerStatus `json:"optimizer,omitempty"`
+	// Consumer is written by the loadbalancer controller that applies spec.clusters.
+	// Loadbalancer controllers should update it (with a patch, so as not to overwrite status.optimizer)
+	// after applying spec.clusters.
+	// +optional
+	Consumer *SLPConsumerStatus `json:"consumer,omitempty"`
+}
+
+// SLPOptimizerStatus represents how spec.clusters was computed.
+type SLPOptimizerStatus struct {
+	// Name is the name of the controller that computed spec.clusters.
+	Name string `json:"name"`
+	// Method is the method used to compute spec.clusters.
+	Method SLPOptimizerMethod `json:"method"`
+	// ObservedGeneration is the metadata.generation of the SLP when spec.clusters was computed.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// LastOptimizedTime is the last time spec.clusters was computed.
+	LastOptimizedTime metav1.Time `json:"lastOptimizedTime"`
+	// ExcludedClusters maps between the clusters excluded from the candidates and the reasons,
+	// one of "NotRegistered", "NotReady" and "Offline".
+	// +optional
+	ExcludedClusters map[string]string `json:"excludedClusters,omitempty"`
+}
+
+// SLPConsumerStatus represents the acknowledgement from the loadbalancer controller that applies spec.clusters.
+type SLPConsumerStatus struct {
+	// Name is the name of the loadbalancer controller.
+	Name string `json:"name"`
+	// AppliedGeneration is the metadata.generation of the SLP whose spec.clusters has been applied.
+	AppliedGeneration int64 `json:"appliedGeneration"`
+	// LastAppliedTime is the last time spec.clusters was applied.
+	// +optional
+	LastAppliedTime metav1.Time `json:"lastAppliedTime,omitempty"`
+	// Message is a human readable message about the last apply (e.g. the reason of a failure).
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:shortName=slp
+//+kubebuilder:printcolumn:name="Method",type=string,JSONPath=`.status.optimizer.method`
+//+kubebuilder:printcolumn:name="Generation",type=integer,JSONPath=`.metadata.generation`
+//+kubebuilder:printcolumn:name="Applied",type=integer,JSONPath=`.status.consumer.appliedGeneration`
+//+kubebuilder:printcolumn:name="Consumer",type=string,JSONPath=`.status.consumer.name`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ServiceLoadbalancingPreference is the Schema for the serviceloadbalancingpreferences API
 type ServiceLoadbalancingPreference struct {
@@ -38,6 +83,11 @@ type ServiceLoadbalancingPreference struct {
 	Status ServiceLoadbalancingPreferenceStatus `json:"status,omitempty"`
 }
 
+// IsApplied reports whether the current spec.clusters has been applied by a loadbalancer controller.
+func (r *ServiceLoadbalancingPreference) IsApplied() bool {
+	return r.Status.Consumer != nil && r.Status.Consumer.AppliedGeneration == r.Generation
+}
+
 //+kubebuilder:object:root=true
 
 // ServiceLoadbalancingPreferenceList contains a list of ServiceLoadbalancingPreference
@@ -0,0 +1,95 @@
+package v1beta1
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// tariffBand is a parsed TariffBand.
+type tariffBand struct {
+	start time.Duration // since midnight
+	price float64
+}
+
+// parse returns the location and the bands sorted by start.
+func (s *TariffSchedule) parse() (*time.Location, []tariffBand, error) {
+	tz := s.TimeZone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeZone %s: %w", tz, err)
+	}
+	if len(s.Bands) == 0 {
+		return nil, nil, fmt.Errorf("bands requires 1 or more items")
+	}
+	bands := make([]tariffBand, len(s.Bands))
+	seen := map[time.Duration]struct{}{}
+	for i, b := range s.Bands {
+		t, err := time.Parse("15:04", b.Start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bands[%d].start %s is not in HH:MM format", i, b.Start)
+		}
+		start := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		if _, ok := seen[start]; ok {
+			return nil, nil, fmt.Errorf("bands[%d].start %s is duplicated", i, b.Start)
+		}
+		seen[start] = struct{}{}
+		price, err := strconv.ParseFloat(b.Price, 64)
+		if err != nil || price < 0 {
+			return nil, nil, fmt.Errorf("bands[%d].price %s is not a non-negative decimal", i, b.Price)
+		}
+		bands[i] = tariffBand{start: start, price: price}
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].start < bands[j].start })
+	return loc, bands, nil
+}
+
+// Validate validates the TariffSchedule.
+func (s *TariffSchedule) Validate() error {
+	_, _, err := s.parse()
+	return err
+}
+
+// PriceAt returns the electricity price at the given time.
+func (s *TariffSchedule) PriceAt(t time.Time) (float64, error) {
+	loc, bands, err := s.parse()
+	if err != nil {
+		return 0, err
+	}
+	t = t.In(loc)
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	// the last band of the previous day lasts until the first band
+	price := bands[len(bands)-1].price
+	for _, b := range bands {
+		if b.start > sinceMidnight {
+			break
+		}
+		price = b.price
+	}
+	return price, nil
+}
+
+// NextChange returns the time when the next band starts after the given time.
+func (s *TariffSchedule) NextChange(t time.Time) (time.Time, error) {
+	loc, bands, err := s.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t = t.In(loc)
+	y, m, d := t.Date()
+	for _, day := range []int{d, d + 1} {
+		for _, b := range bands {
+			// NOTE: use the wall clock so that bands follow DST transitions
+			next := time.Date(y, m, day, int(b.start/time.Hour), int(b.start%time.Hour/time.Minute), 0, 0, loc)
+			if next.After(t) {
+				return next, nil
+			}
+		}
+	}
+	// unreachable as the first band of the next day is always after t
+	return time.Time{}, fmt.Errorf("no next band found")
+}
@@ -0,0 +1,61 @@
+package v1beta1_test
+
+import (
+	"testing"
+	"time"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func TestTariffSchedule(t *testing.T) {
+	tokyo := &v1beta1.TariffSchedule{
+		TimeZone: "Asia/Tokyo", // UTC+9
+		Bands: []v1beta1.TariffBand{
+			{Start: "22:00", Price: "0.15"},
+			{Start: "08:00", Price: "0.30"},
+		},
+	}
+	tests := []struct {
+		name      string
+		schedule  *v1beta1.TariffSchedule
+		now       time.Time
+		wantPrice float64
+		wantNext  time.Time
+		wantErr   bool
+	}{
+		{"day", tokyo, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 0.30, time.Date(2023, 1, 1, 13, 0, 0, 0, time.UTC), false},
+		{"night", tokyo, time.Date(2023, 1, 1, 14, 0, 0, 0, time.UTC), 0.15, time.Date(2023, 1, 1, 23, 0, 0, 0, time.UTC), false},
+		{"after_midnight", tokyo, time.Date(2023, 1, 1, 16, 0, 0, 0, time.UTC), 0.15, time.Date(2023, 1, 1, 23, 0, 0, 0, time.UTC), false},
+		{"band_start", tokyo, time.Date(2023, 1, 1, 13, 0, 0, 0, time.UTC), 0.15, time.Date(2023, 1, 1, 23, 0, 0, 0, time.UTC), false},
+		{"default_utc", &v1beta1.TariffSchedule{Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "1"}}},
+			time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), 1, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), false},
+		{"no_bands", &v1beta1.TariffSchedule{}, time.Time{}, 0, time.Time{}, true},
+		{"invalid_timezone", &v1beta1.TariffSchedule{TimeZone: "Foo/Bar", Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "1"}}}, time.Time{}, 0, time.Time{}, true},
+		{"invalid_start", &v1beta1.TariffSchedule{Bands: []v1beta1.TariffBand{{Start: "24:00", Price: "1"}}}, time.Time{}, 0, time.Time{}, true},
+		{"duplicated_start", &v1beta1.TariffSchedule{Bands: []v1beta1.TariffBand{{Start: "01:00", Price: "1"}, {Start: "01:00", Price: "2"}}}, time.Time{}, 0, time.Time{}, true},
+		{"invalid_price", &v1beta1.TariffSchedule{Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "-1"}}}, time.Time{}, 0, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			price, err := tt.schedule.PriceAt(tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PriceAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if price != tt.wantPrice {
+				t.Errorf("PriceAt() = %v, want %v", price, tt.wantPrice)
+			}
+			next, err := tt.schedule.NextChange(tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NextChange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("NextChange() = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
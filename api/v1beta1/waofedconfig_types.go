@@ -1,6 +1,8 @@
 package v1beta1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -10,13 +12,56 @@ const (
 	DefaultRSPOptimizerAnnotation = "waofed.bitmedia.co.jp/scheduling"
 	DefaultSLPOptimizerAnnotation = "waofed.bitmedia.co.jp/loadbalancing"
 
+	// RSPOptimizerMethodUsedAnnotation is set on generated ReplicaSchedulingPreferences to record the method that computed spec.clusters,
+	// which differs from spec.scheduling.optimizer.method when a fallback method is used.
+	// (SLPs record it in status.optimizer.method.)
+	RSPOptimizerMethodUsedAnnotation = "waofed.bitmedia.co.jp/scheduling-method-used"
+	// RSPExcludedClustersAnnotation is set on generated ReplicaSchedulingPreferences to record the clusters
+	// excluded from the candidates with the reasons in JSON (e.g. {"cluster3": "NotReady"}).
+	// The reason is one of "NotRegistered", "NotReady" and "Offline". (SLPs record it in status.optimizer.excludedClusters.)
+	RSPExcludedClustersAnnotation = "waofed.bitmedia.co.jp/scheduling-excluded-clusters"
+	// RSPLastAppliedWeightsAnnotation is set on generated ReplicaSchedulingPreferences to record the cluster weights
+	// last applied by RSPOptimizer in JSON (e.g. {"cluster1": 2, "cluster2": 1}), which is used for stabilization.
+	RSPLastAppliedWeightsAnnotation = "waofed.bitmedia.co.jp/last-applied-weights"
+	// RSPLastAppliedTimeAnnotation is set on generated ReplicaSchedulingPreferences to record the time
+	// when the cluster weights were last changed by RSPOptimizer in RFC 3339 format.
+	RSPLastAppliedTimeAnnotation = "waofed.bitmedia.co.jp/last-applied-time"
+
+	// WAOEstimatorEndpointAnnotation is the default annotation (or label) of KubeFedClusters
+	// whose value is the WAO-Estimator endpoint of the cluster, used by waoEstimatorDiscovery mode "Annotation".
+	WAOEstimatorEndpointAnnotation = "waofed.bitmedia.co.jp/wao-estimator-endpoint"
+
 	// WAOFedConfigName specifies the name of the only instance of WAOFedConfig that exists in the cluster.
 	WAOFedConfigName = "default"
 
 	waoEstimatorDefaultNamespace = "default"
 	waoEstimatorDefaultName      = "default"
+	waoEstimatorDefaultCacheTTL  = 30 * time.Second
+
+	waoEstimatorServiceDefaultScheme = "http"
 )
 
+// Defaults of waoEstimators set by the webhook, which are also used by the controllers for settings not defaulted by the webhook
+// (e.g. those created before the fields were added).
+const (
+	// DefaultWAOEstimatorTimeout is the default timeout for a request to a WAO-Estimator.
+	DefaultWAOEstimatorTimeout = 10 * time.Second
+	// DefaultWAOEstimatorRetryBackoff is the default wait before the first retry.
+	DefaultWAOEstimatorRetryBackoff = 500 * time.Millisecond
+	// DefaultWAOEstimatorCircuitBreakerFailureThreshold is the default number of consecutive failures to open the circuit.
+	DefaultWAOEstimatorCircuitBreakerFailureThreshold = 5
+	// DefaultWAOEstimatorCircuitBreakerOpenDuration is the default duration the circuit stays open.
+	DefaultWAOEstimatorCircuitBreakerOpenDuration = 1 * time.Minute
+)
+
+// DefaultOptimizerWebhookTimeout is the default timeout for a request to the external optimizer set by the webhook,
+// which is also used by the controllers for settings not defaulted by the webhook.
+const DefaultOptimizerWebhookTimeout = 10 * time.Second
+
+// ResourceSelector selects federated objects.
+//
+// A federated object is selected if it matches any of Any, HasAnnotation and ObjectSelector,
+// and is in a namespace that matches NamespaceSelector and is not listed in ExcludeNamespaces.
 type ResourceSelector struct {
 	// Any matches any FederatedDeployment when set to true. (default: false)
 	// +optional
@@ -24,16 +69,160 @@ type ResourceSelector struct {
 	// HasAnnotation specifies the annotation name within the FederatedDeployment to select. (default: "waofed.bitmedia.co.jp/scheduling")
 	// +optional
 	HasAnnotation *string `json:"hasAnnotation,omitempty"`
+	// ObjectSelector matches the labels of the federated object.
+	// +optional
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// NamespaceSelector limits the selection to federated objects in namespaces whose labels match the selector.
+	// Use it with `any: true` to select all federated objects in the namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// ExcludeNamespaces lists namespaces whose federated objects are never selected. (e.g. ["kube-system"])
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
 }
 
 type WAOEstimatorSetting struct {
 	// Endpoint specifies WAO-Estimator API endpoint.
 	// e.g. "http://localhost:5657"
-	Endpoint string `json:"endpoint"`
+	// Required except in waoEstimatorDiscovery.template, where it is ignored.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
 	// Namespace specifies Estimator resource namespace. (default: "default")
 	Namespace string `json:"namespace,omitempty"`
 	// Name specifies Estimator resource name. (default: "default")
 	Name string `json:"name,omitempty"`
+
+	// CABundle specifies the PEM encoded CA bundle used to verify the server certificate of an https endpoint.
+	// The system trust roots are used if not specified.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// InsecureSkipVerify disables the server certificate verification. (default: false)
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// ClientCertSecret specifies a Secret of type kubernetes.io/tls whose "tls.crt" and "tls.key"
+	// are used as the client certificate for mutual TLS.
+	// +optional
+	ClientCertSecret *SecretReference `json:"clientCertSecret,omitempty"`
+
+	// BearerTokenSecret specifies a Secret whose "token" is sent as a bearer token.
+	// +optional
+	BearerTokenSecret *SecretReference `json:"bearerTokenSecret,omitempty"`
+
+	// BasicAuthSecret specifies a Secret of type kubernetes.io/basic-auth whose "username" and "password"
+	// are sent with the basic authentication. Cannot be specified with bearerTokenSecret.
+	// +optional
+	BasicAuthSecret *SecretReference `json:"basicAuthSecret,omitempty"`
+
+	// CacheTTL specifies how long estimated power increases are reused for the same cluster, CPU requests and replicas,
+	// e.g. while many FederatedDeployments are being updated. "0s" disables the cache. (default: "30s")
+	// +optional
+	CacheTTL *metav1.Duration `json:"cacheTTL,omitempty"`
+
+	// RateLimit limits the requests sent to the endpoint. (default: no limit)
+	// +optional
+	RateLimit *WAOEstimatorRateLimit `json:"rateLimit,omitempty"`
+
+	// Timeout specifies the timeout of a request including the wait for the rate limit (e.g. "5s"). (default: "10s")
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retry specifies how to retry failed requests. (default: no retry)
+	// +optional
+	Retry *WAOEstimatorRetry `json:"retry,omitempty"`
+
+	// CircuitBreaker stops sending requests to the WAO-Estimator after consecutive failures. (default: disabled)
+	// +optional
+	CircuitBreaker *WAOEstimatorCircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// WAOEstimatorRateLimit specifies the rate limit of the requests sent to a WAO-Estimator endpoint.
+// The limit is shared by all clusters and controllers that use the same endpoint;
+// if they specify different limits, the most recently used one takes effect.
+type WAOEstimatorRateLimit struct {
+	// QPS specifies the maximum average number of requests per second.
+	QPS int32 `json:"qps"`
+
+	// Burst specifies the maximum number of requests sent at once. (default: same as qps)
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// WAOEstimatorRetry specifies how to retry failed requests to a WAO-Estimator.
+// Errors returned by the WAO-Estimator API (e.g. the Estimator not found) are not retried.
+type WAOEstimatorRetry struct {
+	// MaxRetries specifies the maximum number of retries of a failed request.
+	MaxRetries int32 `json:"maxRetries"`
+
+	// Backoff specifies the wait before the first retry, which is doubled on each retry (e.g. "1s"). (default: "500ms")
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+}
+
+// WAOEstimatorCircuitBreaker specifies when to stop sending requests to a failing WAO-Estimator.
+//
+// The circuit opens after FailureThreshold consecutive failed requests (after retries),
+// and no requests are sent while it is open. After OpenDuration, a trial request is sent,
+// which closes the circuit on success or opens it again on failure.
+// Clusters with open circuits are treated as their WAO-Estimators failed.
+type WAOEstimatorCircuitBreaker struct {
+	// FailureThreshold specifies the number of consecutive failed requests to open the circuit. (default: 5)
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// OpenDuration specifies how long the circuit stays open before a trial request (e.g. "30s"). (default: "1m")
+	// +optional
+	OpenDuration *metav1.Duration `json:"openDuration,omitempty"`
+}
+
+// WAOEstimatorDiscoveryMode specifies how to discover the WAO-Estimator of a member cluster.
+type WAOEstimatorDiscoveryMode string
+
+const (
+	// WAOEstimatorDiscoveryModeAnnotation takes the endpoint from an annotation (or label) of the KubeFedCluster.
+	WAOEstimatorDiscoveryModeAnnotation = "Annotation"
+	// WAOEstimatorDiscoveryModeService reaches the WAO-Estimator Service in the member cluster
+	// through the API server proxy with the API endpoint and the secret of the KubeFedCluster.
+	WAOEstimatorDiscoveryModeService = "Service"
+)
+
+// WAOEstimatorDiscovery specifies how to discover WAO-Estimators of member clusters not listed in waoEstimators,
+// so that clusters joining the federation are optimized without editing WAOFedConfig.
+type WAOEstimatorDiscovery struct {
+	// Mode specifies the discovery mode, "Annotation" or "Service". (default: "Annotation")
+	// +optional
+	Mode WAOEstimatorDiscoveryMode `json:"mode,omitempty"`
+
+	// Key specifies the annotation or label key of KubeFedClusters whose value is the WAO-Estimator endpoint,
+	// used by mode "Annotation". Annotations take precedence over labels, and "http://" is assumed if the value has no scheme
+	// (label values cannot contain "://", so labels can only specify a host name).
+	// (default: "waofed.bitmedia.co.jp/wao-estimator-endpoint")
+	// +optional
+	Key string `json:"key,omitempty"`
+
+	// Service specifies the WAO-Estimator Service in member clusters, used by mode "Service".
+	// +optional
+	Service *WAOEstimatorServiceReference `json:"service,omitempty"`
+
+	// Template specifies the settings of discovered WAO-Estimators except the endpoint, e.g. namespace, name and cacheTTL.
+	// With mode "Service", the TLS and authentication settings are taken from the KubeFedCluster and cannot be specified.
+	// +optional
+	Template *WAOEstimatorSetting `json:"template,omitempty"`
+}
+
+// WAOEstimatorServiceReference specifies a WAO-Estimator Service in member clusters.
+type WAOEstimatorServiceReference struct {
+	// Namespace specifies the Service namespace.
+	Namespace string `json:"namespace"`
+	// Name specifies the Service name.
+	Name string `json:"name"`
+	// Port specifies the Service port name or number. The only port of the Service is used if not specified.
+	// +optional
+	Port string `json:"port,omitempty"`
+	// Scheme specifies the scheme the API server uses to connect to the Service, "http" or "https". (default: "http")
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
 }
 
 type RSPOptimizerMethod string
@@ -41,20 +230,244 @@ type RSPOptimizerMethod string
 const (
 	RSPOptimizerMethodRoundRobin = "rr"
 	RSPOptimizerMethodWAO        = "wao"
+	RSPOptimizerMethodCapacity   = "capacity"
+	RSPOptimizerMethodCarbon     = "carbon"
+	RSPOptimizerMethodPrice      = "price"
+	RSPOptimizerMethodWebhook    = "webhook"
 )
 
+// ConfigMapReference specifies a ConfigMap.
+type ConfigMapReference struct {
+	// Namespace specifies the ConfigMap namespace.
+	Namespace string `json:"namespace"`
+	// Name specifies the ConfigMap name.
+	Name string `json:"name"`
+}
+
+// SecretReference specifies a Secret.
+type SecretReference struct {
+	// Namespace specifies the Secret namespace.
+	Namespace string `json:"namespace"`
+	// Name specifies the Secret name.
+	Name string `json:"name"`
+}
+
+// CarbonIntensitySource specifies where to get the grid carbon intensity (gCO2/kWh) of member clusters.
+// Exactly one of Static, ConfigMap and Endpoint must be specified.
+// In all sources, "*" specifies the carbon intensity for clusters not listed explicitly.
+type CarbonIntensitySource struct {
+	// Static specifies the carbon intensity of each cluster.
+	//
+	// e.g. { cluster1: 300, cluster2: 50 }
+	//
+	// +optional
+	Static map[string]int64 `json:"static,omitempty"`
+
+	// ConfigMap specifies a ConfigMap whose data has cluster names as keys and carbon intensities as values.
+	//
+	// e.g. data: { cluster1: "300", cluster2: "52.5" }
+	//
+	// +optional
+	ConfigMap *ConfigMapReference `json:"configMap,omitempty"`
+
+	// Endpoint specifies an HTTP endpoint that returns a JSON object with cluster names as keys and carbon intensities as values.
+	//
+	// e.g. GET http://carbon.example.com/intensity -> {"cluster1": 300, "cluster2": 52.5}
+	//
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// UseWAOEstimators minimizes the total emissions estimated by multiplying the power increases by WAO-Estimators
+	// by the carbon intensity of each cluster. Otherwise, clusters are weighted by the inverse of the carbon intensity.
+	// Requires waoEstimators when set to true. (default: false)
+	// +optional
+	UseWAOEstimators bool `json:"useWAOEstimators,omitempty"`
+}
+
+// TariffBand specifies the electricity price from a time of day until the start of the next band.
+type TariffBand struct {
+	// Start specifies the start time of the band in "HH:MM" format (e.g. "08:00").
+	Start string `json:"start"`
+	// Price specifies the electricity price per kWh in decimal (e.g. "0.25").
+	// Any currency can be used as long as all clusters use the same one.
+	Price string `json:"price"`
+}
+
+// TariffSchedule specifies the time-of-use electricity prices of a day.
+type TariffSchedule struct {
+	// TimeZone specifies the IANA time zone name of the bands (e.g. "Asia/Tokyo"). (default: "UTC")
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Bands specifies the price bands of a day.
+	// The last band of a day lasts until the start of the first band of the next day.
+	Bands []TariffBand `json:"bands"`
+}
+
+// OptimizerWebhook specifies an external optimizer called with method "webhook".
+// WAOFed POSTs an OptimizeRequest to the URL and expects an OptimizeResponse.
+type OptimizerWebhook struct {
+	// URL specifies the http or https URL of the external optimizer (e.g. "https://optimizer.example.com/optimize").
+	URL string `json:"url"`
+
+	// CABundle specifies the PEM encoded CA bundle used to verify the server certificate.
+	// The system trust roots are used if not specified.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// InsecureSkipVerify disables the server certificate verification. (default: false)
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// Timeout specifies the timeout of a request (e.g. "5s"). (default: "10s")
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// RSPStabilization specifies how to suppress frequent changes of cluster weights,
+// which make KubeFed move pods back and forth between clusters.
+// Changes of the candidate clusters or the total replicas are always applied immediately.
+type RSPStabilization struct {
+	// Window specifies the minimum time between changes of cluster weights (e.g. "10m").
+	// A change within the window is deferred until the window elapses. (default: no limit)
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+
+	// MinImprovement specifies the minimum improvement of the estimated cost required to change cluster weights
+	// in decimal (e.g. "5" for a 5 W saving with method "wao").
+	// The unit is the cost minimized by the method, that is watts for "wao", watts multiplied by the carbon intensity
+	// for "carbon" (with useWAOEstimators), and watts multiplied by the price for "price".
+	// Ignored by methods that do not estimate costs. (default: no threshold)
+	// +optional
+	MinImprovement string `json:"minImprovement,omitempty"`
+}
+
+type DefaultPlacementType string
+
+const (
+	DefaultPlacementNone            = "none"
+	DefaultPlacementAllClusters     = "allClusters"
+	DefaultPlacementClusterSelector = "clusterSelector"
+)
+
+// DefaultPlacement specifies the candidate clusters for federated objects without spec.placement,
+// e.g. those relying on the placement of the FederatedNamespace.
+type DefaultPlacement struct {
+	// Type specifies the candidate clusters, one of "none" (no clusters), "allClusters" (all KubeFedClusters)
+	// and "clusterSelector" (KubeFedClusters selected by clusterSelector). (default: "none")
+	// +optional
+	Type DefaultPlacementType `json:"type,omitempty"`
+
+	// ClusterSelector selects KubeFedClusters by their labels.
+	// Required when type "clusterSelector" is specified.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+}
+
+// ReplicaBounds specifies the range of the number of replicas scheduled on a cluster.
+type ReplicaBounds struct {
+	// MinReplicas specifies the minimum number of replicas scheduled on the cluster. (default: 0)
+	// +optional
+	MinReplicas int64 `json:"minReplicas,omitempty"`
+	// MaxReplicas specifies the maximum number of replicas scheduled on the cluster. (default: unbounded)
+	// +optional
+	MaxReplicas *int64 `json:"maxReplicas,omitempty"`
+}
+
 type RSPOptimizerSettings struct {
 	// Method specifies the method name to use. (default: "rr")
 	// +optional
 	Method *RSPOptimizerMethod `json:"method,omitempty"`
 
 	// WAOEstimators specifies WAO-Estimator settings for member clusters.
-	// Required when method "wao" is specified.
+	// Required when method "wao" is specified, unless waoEstimatorDiscovery is specified.
 	//
 	// e.g. { cluster1: {endpoint: "http://localhost:5657"}, cluster2: {endpoint: "http://localhost:5658"} }
 	//
 	// +optional
 	WAOEstimators map[string]*WAOEstimatorSetting `json:"waoEstimators,omitempty"`
+
+	// WAOEstimatorDiscovery discovers WAO-Estimators of member clusters not listed in waoEstimators.
+	// Either waoEstimators or waoEstimatorDiscovery is required when WAO-Estimators are used.
+	// +optional
+	WAOEstimatorDiscovery *WAOEstimatorDiscovery `json:"waoEstimatorDiscovery,omitempty"`
+
+	// ReoptimizeInterval specifies the interval to re-optimize cluster weights periodically (e.g. "10m").
+	// Cluster weights are optimized only when related resources change if not specified or zero.
+	// +optional
+	ReoptimizeInterval *metav1.Duration `json:"reoptimizeInterval,omitempty"`
+
+	// ReplicaBounds specifies the minimum and maximum number of replicas for member clusters,
+	// which are respected by all methods and copied into the generated ReplicaSchedulingPreferences.
+	// "*" specifies the bounds for clusters not listed explicitly.
+	//
+	// e.g. { "*": {minReplicas: 1}, edge1: {maxReplicas: 2} }
+	//
+	// +optional
+	ReplicaBounds map[string]ReplicaBounds `json:"replicaBounds,omitempty"`
+
+	// CarbonIntensity specifies the source of the grid carbon intensity of member clusters.
+	// Required when method "carbon" is specified.
+	// +optional
+	CarbonIntensity *CarbonIntensitySource `json:"carbonIntensity,omitempty"`
+
+	// Tariffs specifies the time-of-use electricity prices of member clusters.
+	// Required when method "price" is specified (with waoEstimators).
+	// "*" specifies the tariff for clusters not listed explicitly.
+	//
+	// e.g. { cluster1: {timeZone: "Asia/Tokyo", bands: [{start: "08:00", price: "0.30"}, {start: "22:00", price: "0.15"}]} }
+	//
+	// +optional
+	Tariffs map[string]TariffSchedule `json:"tariffs,omitempty"`
+
+	// Webhook specifies the external optimizer.
+	// Required when method "webhook" is specified.
+	// +optional
+	Webhook *OptimizerWebhook `json:"webhook,omitempty"`
+
+	// Stabilization specifies how to suppress frequent changes of cluster weights.
+	// +optional
+	Stabilization *RSPStabilization `json:"stabilization,omitempty"`
+
+	// DefaultPlacement specifies the candidate clusters for FederatedDeployments without spec.placement.
+	// No clusters are candidates if not specified.
+	// +optional
+	DefaultPlacement *DefaultPlacement `json:"defaultPlacement,omitempty"`
+
+	// Rebalance is copied into spec.rebalance of the generated ReplicaSchedulingPreferences. (default: true)
+	// If false, KubeFed does not move running replicas between clusters,
+	// and WAOFed only optimizes the placement of replicas added by scaling up, keeping the current placement.
+	// +optional
+	Rebalance *bool `json:"rebalance,omitempty"`
+
+	// IntersectWithClusterSelector is copied into spec.intersectWithClusterSelector of the generated ReplicaSchedulingPreferences. (default: true)
+	// If false, KubeFed places replicas on the clusters in the RSP regardless of spec.placement.clusterSelector of the federated object.
+	// +optional
+	IntersectWithClusterSelector *bool `json:"intersectWithClusterSelector,omitempty"`
+
+	// FallbackMethods specifies the methods to try in order when the method fails
+	// (e.g. all WAO-Estimators or the webhook are unreachable).
+	// Methods already tried are skipped, so [wao, capacity, rr] can be used with method "wao".
+	// The settings required by the fallback methods must also be specified.
+	// The RSP is not updated if all methods fail.
+	// +optional
+	FallbackMethods []RSPOptimizerMethod `json:"fallbackMethods,omitempty"`
+}
+
+// Methods returns the method followed by the fallback methods without duplicates.
+func (s *RSPOptimizerSettings) Methods() []RSPOptimizerMethod {
+	var out []RSPOptimizerMethod
+	seen := map[RSPOptimizerMethod]struct{}{}
+	if s.Method != nil {
+		out = append(out, *s.Method)
+		seen[*s.Method] = struct{}{}
+	}
+	for _, m := range s.FallbackMethods {
+		if _, ok := seen[m]; !ok {
+			out = append(out, m)
+			seen[m] = struct{}{}
+		}
+	}
+	return out
 }
 
 type SchedulingSettings struct {
@@ -71,6 +484,7 @@ type SLPOptimizerMethod string
 const (
 	SLPOptimizerMethodRoundRobin = "rr"
 	SLPOptimizerMethodWAO        = "wao"
+	SLPOptimizerMethodWebhook    = "webhook"
 )
 
 type SLPOptimizerSettings struct {
@@ -79,12 +493,52 @@ type SLPOptimizerSettings struct {
 	Method *SLPOptimizerMethod `json:"method,omitempty"`
 
 	// WAOEstimators specifies WAO-Estimator settings for member clusters.
-	// Required when method "wao" is specified.
+	// Required when method "wao" is specified, unless waoEstimatorDiscovery is specified.
 	//
 	// e.g. { cluster1: {endpoint: "http://localhost:5657"}, cluster2: {endpoint: "http://localhost:5658"} }
 	//
 	// +optional
 	WAOEstimators map[string]*WAOEstimatorSetting `json:"waoEstimators,omitempty"`
+
+	// WAOEstimatorDiscovery discovers WAO-Estimators of member clusters not listed in waoEstimators.
+	// Either waoEstimators or waoEstimatorDiscovery is required when WAO-Estimators are used.
+	// +optional
+	WAOEstimatorDiscovery *WAOEstimatorDiscovery `json:"waoEstimatorDiscovery,omitempty"`
+
+	// ReoptimizeInterval specifies the interval to re-optimize cluster weights periodically (e.g. "10m").
+	// Cluster weights are optimized only when related resources change if not specified or zero.
+	// +optional
+	ReoptimizeInterval *metav1.Duration `json:"reoptimizeInterval,omitempty"`
+
+	// Webhook specifies the external optimizer.
+	// Required when method "webhook" is specified.
+	// +optional
+	Webhook *OptimizerWebhook `json:"webhook,omitempty"`
+
+	// FallbackMethods specifies the methods to try in order when the method fails
+	// (e.g. all WAO-Estimators or the webhook are unreachable).
+	// Methods already tried are skipped, so [wao, rr] can be used with method "wao".
+	// The settings required by the fallback methods must also be specified.
+	// The SLP is not updated if all methods fail.
+	// +optional
+	FallbackMethods []SLPOptimizerMethod `json:"fallbackMethods,omitempty"`
+}
+
+// Methods returns the method followed by the fallback methods without duplicates.
+func (s *SLPOptimizerSettings) Methods() []SLPOptimizerMethod {
+	var out []SLPOptimizerMethod
+	seen := map[SLPOptimizerMethod]struct{}{}
+	if s.Method != nil {
+		out = append(out, *s.Method)
+		seen[*s.Method] = struct{}{}
+	}
+	for _, m := range s.FallbackMethods {
+		if _, ok := seen[m]; !ok {
+			out = append(out, m)
+			seen[m] = struct{}{}
+		}
+	}
+	return out
 }
 
 type LoadBalancingSettings struct {
@@ -110,15 +564,70 @@ type WAOFedConfigSpec struct {
 	LoadBalancing *LoadBalancingSettings `json:"loadbalancing,omitempty"`
 }
 
+const (
+	// WAOFedConfigConditionReady is true when all other conditions are true.
+	WAOFedConfigConditionReady = "Ready"
+	// WAOFedConfigConditionKubeFedNamespaceFound is true when spec.kubefedNamespace exists.
+	WAOFedConfigConditionKubeFedNamespaceFound = "KubeFedNamespaceFound"
+	// WAOFedConfigConditionEstimatorsReachable is true when all WAO-Estimators used by "wao" methods are reachable.
+	WAOFedConfigConditionEstimatorsReachable = "EstimatorsReachable"
+)
+
+// EstimatorStatus represents the observed health of a WAO-Estimator.
+type EstimatorStatus struct {
+	// Endpoint is the WAO-Estimator API endpoint.
+	Endpoint string `json:"endpoint"`
+	// Reachable is true if the WAO-Estimator responded to the last probe.
+	Reachable bool `json:"reachable"`
+	// Message is a human readable message indicating why the WAO-Estimator is not reachable.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// CircuitOpen is true if the circuit breaker of the WAO-Estimator is open,
+	// that is the optimizers do not send requests to it regardless of the probe result.
+	// +optional
+	CircuitOpen bool `json:"circuitOpen,omitempty"`
+	// LastProbeTime is the last time the WAO-Estimator was probed.
+	// +optional
+	LastProbeTime metav1.Time `json:"lastProbeTime,omitempty"`
+}
+
+// ClusterStatus represents the observed state of a KubeFedCluster.
+type ClusterStatus struct {
+	// Name is the KubeFedCluster name.
+	Name string `json:"name"`
+	// Ready is true if the KubeFedCluster has the Ready condition set to true.
+	Ready bool `json:"ready"`
+	// SchedulingEstimator is the WAO-Estimator health used by spec.scheduling with method "wao".
+	// +optional
+	SchedulingEstimator *EstimatorStatus `json:"schedulingEstimator,omitempty"`
+	// LoadBalancingEstimator is the WAO-Estimator health used by spec.loadbalancing with method "wao".
+	// +optional
+	LoadBalancingEstimator *EstimatorStatus `json:"loadbalancingEstimator,omitempty"`
+}
+
 // WAOFedConfigStatus defines the observed state of WAOFedConfig
 type WAOFedConfigStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ObservedGeneration is the most recent generation observed by WAOFed.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the WAOFedConfig.
+	// Known condition types are "Ready", "KubeFedNamespaceFound" and "EstimatorsReachable".
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Clusters lists KubeFedClusters discovered in spec.kubefedNamespace.
+	// +optional
+	Clusters []ClusterStatus `json:"clusters,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster,shortName=waofed;wfc
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // WAOFedConfig is the Schema for the waofedconfigs API
 type WAOFedConfig struct {
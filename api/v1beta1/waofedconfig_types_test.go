@@ -0,0 +1,42 @@
+package v1beta1_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func TestRSPOptimizerSettings_Methods(t *testing.T) {
+	wao := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodWAO)
+	tests := []struct {
+		name     string
+		settings *v1beta1.RSPOptimizerSettings
+		want     []v1beta1.RSPOptimizerMethod
+	}{
+		{"empty", &v1beta1.RSPOptimizerSettings{}, nil},
+		{"method", &v1beta1.RSPOptimizerSettings{Method: &wao}, []v1beta1.RSPOptimizerMethod{"wao"}},
+		{"fallback", &v1beta1.RSPOptimizerSettings{Method: &wao, FallbackMethods: []v1beta1.RSPOptimizerMethod{"capacity", "rr"}},
+			[]v1beta1.RSPOptimizerMethod{"wao", "capacity", "rr"}},
+		{"dedup", &v1beta1.RSPOptimizerSettings{Method: &wao, FallbackMethods: []v1beta1.RSPOptimizerMethod{"wao", "capacity", "rr", "capacity"}},
+			[]v1beta1.RSPOptimizerMethod{"wao", "capacity", "rr"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.settings.Methods()
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("Methods() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func TestSLPOptimizerSettings_Methods(t *testing.T) {
+	wao := v1beta1.SLPOptimizerMethod(v1beta1.SLPOptimizerMethodWAO)
+	got := (&v1beta1.SLPOptimizerSettings{Method: &wao, FallbackMethods: []v1beta1.SLPOptimizerMethod{"wao", "webhook", "rr"}}).Methods()
+	want := []v1beta1.SLPOptimizerMethod{"wao", "webhook", "rr"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Methods() = %v, want %v, diff %s", got, want, diff)
+	}
+}
@@ -1,10 +1,16 @@
 package v1beta1
 
 import (
+	"crypto/x509"
 	"fmt"
+	"math"
 	"net/url"
+	"strconv"
+	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -56,20 +62,92 @@ func (r *WAOFedConfig) defaultScheduling() {
 	if r.Spec.Scheduling.Optimizer.Method == nil {
 		r.Spec.Scheduling.Optimizer.Method = (*RSPOptimizerMethod)(pointer.String(RSPOptimizerMethodRoundRobin))
 	}
+	if dp := r.Spec.Scheduling.Optimizer.DefaultPlacement; dp != nil && dp.Type == "" {
+		if dp.ClusterSelector != nil {
+			dp.Type = DefaultPlacementClusterSelector
+		} else {
+			dp.Type = DefaultPlacementNone
+		}
+	}
+	if r.Spec.Scheduling.Optimizer.Rebalance == nil {
+		r.Spec.Scheduling.Optimizer.Rebalance = pointer.Bool(true)
+	}
+	if r.Spec.Scheduling.Optimizer.IntersectWithClusterSelector == nil {
+		r.Spec.Scheduling.Optimizer.IntersectWithClusterSelector = pointer.Bool(true)
+	}
 
 	// optimizer specific settings
-	switch *r.Spec.Scheduling.Optimizer.Method {
-	case RSPOptimizerMethodRoundRobin:
-	case RSPOptimizerMethodWAO:
-		for _, v := range r.Spec.Scheduling.Optimizer.WAOEstimators {
-			if v.Namespace == "" {
-				v.Namespace = waoEstimatorDefaultNamespace
-			}
-			if v.Name == "" {
-				v.Name = waoEstimatorDefaultName
-			}
+	defaultRSPOptimizerMethodSettings(r.Spec.Scheduling.Optimizer)
+}
+
+// defaultRSPOptimizerMethodSettings defaults the settings of all methods including those not used by method or fallbackMethods,
+// as the method can be overridden per object (Ref. RSPOptimizerSettings.WithOverrides).
+func defaultRSPOptimizerMethodSettings(s *RSPOptimizerSettings) {
+	defaultWAOEstimators(s.WAOEstimators)
+	defaultWAOEstimatorDiscovery(s.WAOEstimatorDiscovery)
+	defaultOptimizerWebhook(s.Webhook)
+}
+
+func defaultWAOEstimators(es map[string]*WAOEstimatorSetting) {
+	for _, v := range es {
+		defaultWAOEstimator(v)
+	}
+}
+
+func defaultWAOEstimator(v *WAOEstimatorSetting) {
+	if v.Namespace == "" {
+		v.Namespace = waoEstimatorDefaultNamespace
+	}
+	if v.Name == "" {
+		v.Name = waoEstimatorDefaultName
+	}
+	if v.CacheTTL == nil {
+		v.CacheTTL = &metav1.Duration{Duration: waoEstimatorDefaultCacheTTL}
+	}
+	if v.RateLimit != nil && v.RateLimit.Burst == 0 {
+		v.RateLimit.Burst = v.RateLimit.QPS
+	}
+	if v.Timeout == nil {
+		v.Timeout = &metav1.Duration{Duration: DefaultWAOEstimatorTimeout}
+	}
+	if v.Retry != nil && v.Retry.Backoff == nil {
+		v.Retry.Backoff = &metav1.Duration{Duration: DefaultWAOEstimatorRetryBackoff}
+	}
+	if cb := v.CircuitBreaker; cb != nil {
+		if cb.FailureThreshold == 0 {
+			cb.FailureThreshold = DefaultWAOEstimatorCircuitBreakerFailureThreshold
 		}
-	default:
+		if cb.OpenDuration == nil {
+			cb.OpenDuration = &metav1.Duration{Duration: DefaultWAOEstimatorCircuitBreakerOpenDuration}
+		}
+	}
+}
+
+func defaultWAOEstimatorDiscovery(d *WAOEstimatorDiscovery) {
+	if d == nil {
+		return
+	}
+	if d.Mode == "" {
+		d.Mode = WAOEstimatorDiscoveryModeAnnotation
+	}
+	if d.Mode == WAOEstimatorDiscoveryModeAnnotation && d.Key == "" {
+		d.Key = WAOEstimatorEndpointAnnotation
+	}
+	if d.Service != nil && d.Service.Scheme == "" {
+		d.Service.Scheme = waoEstimatorServiceDefaultScheme
+	}
+	if d.Template == nil {
+		d.Template = &WAOEstimatorSetting{}
+	}
+	defaultWAOEstimator(d.Template)
+}
+
+func defaultOptimizerWebhook(wh *OptimizerWebhook) {
+	if wh == nil {
+		return
+	}
+	if wh.Timeout == nil {
+		wh.Timeout = &metav1.Duration{Duration: DefaultOptimizerWebhookTimeout}
 	}
 }
 
@@ -96,19 +174,15 @@ func (r *WAOFedConfig) defaultLoadbalancing() {
 	}
 
 	// optimizer specific settings
-	switch *r.Spec.LoadBalancing.Optimizer.Method {
-	case SLPOptimizerMethodRoundRobin:
-	case SLPOptimizerMethodWAO:
-		for _, v := range r.Spec.LoadBalancing.Optimizer.WAOEstimators {
-			if v.Namespace == "" {
-				v.Namespace = waoEstimatorDefaultNamespace
-			}
-			if v.Name == "" {
-				v.Name = waoEstimatorDefaultName
-			}
-		}
-	default:
-	}
+	defaultSLPOptimizerMethodSettings(r.Spec.LoadBalancing.Optimizer)
+}
+
+// defaultSLPOptimizerMethodSettings defaults the settings of all methods including those not used by method or fallbackMethods,
+// as the method can be overridden per object (Ref. SLPOptimizerSettings.WithOverrides).
+func defaultSLPOptimizerMethodSettings(s *SLPOptimizerSettings) {
+	defaultWAOEstimators(s.WAOEstimators)
+	defaultWAOEstimatorDiscovery(s.WAOEstimatorDiscovery)
+	defaultOptimizerWebhook(s.Webhook)
 }
 
 //+kubebuilder:webhook:path=/validate-waofed-bitmedia-co-jp-v1beta1-waofedconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=waofed.bitmedia.co.jp,resources=waofedconfigs,verbs=create;update;delete,versions=v1beta1,name=vwaofedconfig.kb.io,admissionReviewVersions=v1
@@ -180,6 +254,20 @@ func (r *WAOFedConfig) validateKubeFedNS() error {
 	return nil
 }
 
+// validateWAOEstimatorSources validates waoEstimators and waoEstimatorDiscovery of the optimizer settings at jsonPath.
+// waoEstimators may be empty if waoEstimatorDiscovery is specified.
+func validateWAOEstimatorSources(es map[string]*WAOEstimatorSetting, d *WAOEstimatorDiscovery, jsonPath string) error {
+	if d == nil || len(es) > 0 {
+		if err := validateWAOEstimators(es, jsonPath+".waoEstimators"); err != nil {
+			return err
+		}
+	}
+	if d != nil {
+		return validateWAOEstimatorDiscovery(d, jsonPath+".waoEstimatorDiscovery")
+	}
+	return nil
+}
+
 func validateWAOEstimators(es map[string]*WAOEstimatorSetting, jsonPath string) error {
 	if len(es) == 0 {
 		return fmt.Errorf("%s requires 1 or more items", jsonPath)
@@ -188,33 +276,351 @@ func validateWAOEstimators(es map[string]*WAOEstimatorSetting, jsonPath string)
 		if k == "" {
 			return fmt.Errorf("%s cannot use empty string as key", jsonPath)
 		}
-		if _, err := url.ParseRequestURI(v.Endpoint); err != nil {
+		u, err := url.ParseRequestURI(v.Endpoint)
+		if err != nil {
 			return fmt.Errorf("%s[k] is not a valid URL: %w", jsonPath, err)
 		}
+		if err := validateWAOEstimatorSecurity(v, u, fmt.Sprintf("%s[%s]", jsonPath, k)); err != nil {
+			return err
+		}
+		if err := validateWAOEstimator(v, fmt.Sprintf("%s[%s]", jsonPath, k)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWAOEstimator validates the settings of the WAO-Estimator except the endpoint and the TLS and authentication settings.
+func validateWAOEstimator(v *WAOEstimatorSetting, jsonPath string) error {
+	if v.CacheTTL != nil && v.CacheTTL.Duration < 0 {
+		return fmt.Errorf("%s.cacheTTL must not be negative", jsonPath)
+	}
+	if rl := v.RateLimit; rl != nil {
+		if rl.QPS <= 0 {
+			return fmt.Errorf("%s.rateLimit.qps must be positive", jsonPath)
+		}
+		if rl.Burst < 0 {
+			return fmt.Errorf("%s.rateLimit.burst must not be negative", jsonPath)
+		}
+	}
+	if v.Timeout != nil && v.Timeout.Duration <= 0 {
+		return fmt.Errorf("%s.timeout must be positive", jsonPath)
+	}
+	if r := v.Retry; r != nil {
+		if r.MaxRetries < 0 {
+			return fmt.Errorf("%s.retry.maxRetries must not be negative", jsonPath)
+		}
+		if r.Backoff != nil && r.Backoff.Duration < 0 {
+			return fmt.Errorf("%s.retry.backoff must not be negative", jsonPath)
+		}
+	}
+	if cb := v.CircuitBreaker; cb != nil {
+		if cb.FailureThreshold < 0 {
+			return fmt.Errorf("%s.circuitBreaker.failureThreshold must not be negative", jsonPath)
+		}
+		if cb.OpenDuration != nil && cb.OpenDuration.Duration <= 0 {
+			return fmt.Errorf("%s.circuitBreaker.openDuration must be positive", jsonPath)
+		}
+	}
+	return nil
+}
+
+func validateWAOEstimatorDiscovery(d *WAOEstimatorDiscovery, jsonPath string) error {
+	switch d.Mode {
+	case WAOEstimatorDiscoveryModeAnnotation:
+		if errs := validation.IsQualifiedName(d.Key); len(errs) > 0 {
+			return fmt.Errorf("%s.key is invalid: %s", jsonPath, strings.Join(errs, ", "))
+		}
+	case WAOEstimatorDiscoveryModeService:
+		svc := d.Service
+		if svc == nil || svc.Namespace == "" || svc.Name == "" {
+			return fmt.Errorf("%s.service requires namespace and name with mode %s", jsonPath, d.Mode)
+		}
+		if svc.Scheme != "http" && svc.Scheme != "https" {
+			return fmt.Errorf("%s.service.scheme must be http or https", jsonPath)
+		}
+		if t := d.Template; t != nil && (len(t.CABundle) > 0 || t.InsecureSkipVerify ||
+			t.ClientCertSecret != nil || t.BearerTokenSecret != nil || t.BasicAuthSecret != nil) {
+			return fmt.Errorf("%s.template cannot specify TLS or authentication settings with mode %s", jsonPath, d.Mode)
+		}
+	default:
+		return fmt.Errorf("invalid %s.mode %s", jsonPath, d.Mode)
+	}
+	if d.Template != nil {
+		// NOTE: discovered endpoints must be https to use the TLS and authentication settings, which is checked on discovery
+		if err := validateWAOEstimatorSecurity(d.Template, &url.URL{Scheme: "https"}, jsonPath+".template"); err != nil {
+			return err
+		}
+		if err := validateWAOEstimator(d.Template, jsonPath+".template"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateWAOEstimatorSecurity validates the TLS and authentication settings of the WAO-Estimator.
+// They require an https endpoint so that credentials are never sent in plain text.
+func validateWAOEstimatorSecurity(v *WAOEstimatorSetting, u *url.URL, jsonPath string) error {
+	secured := len(v.CABundle) > 0 || v.InsecureSkipVerify ||
+		v.ClientCertSecret != nil || v.BearerTokenSecret != nil || v.BasicAuthSecret != nil
+	if secured && u.Scheme != "https" {
+		return fmt.Errorf("%s.endpoint must be https to use TLS or authentication settings", jsonPath)
+	}
+	if len(v.CABundle) > 0 && !x509.NewCertPool().AppendCertsFromPEM(v.CABundle) {
+		return fmt.Errorf("%s.caBundle contains no valid PEM encoded certificates", jsonPath)
+	}
+	if v.BearerTokenSecret != nil && v.BasicAuthSecret != nil {
+		return fmt.Errorf("%s cannot specify both bearerTokenSecret and basicAuthSecret", jsonPath)
+	}
+	for _, ref := range []struct {
+		name string
+		ref  *SecretReference
+	}{
+		{"clientCertSecret", v.ClientCertSecret},
+		{"bearerTokenSecret", v.BearerTokenSecret},
+		{"basicAuthSecret", v.BasicAuthSecret},
+	} {
+		if ref.ref != nil && (ref.ref.Namespace == "" || ref.ref.Name == "") {
+			return fmt.Errorf("%s.%s requires namespace and name", jsonPath, ref.name)
+		}
+	}
+	return nil
+}
+
+func validateResourceSelector(sel *ResourceSelector, jsonPath string) error {
+	if _, err := metav1.LabelSelectorAsSelector(sel.ObjectSelector); err != nil {
+		return fmt.Errorf("%s.objectSelector is invalid: %w", jsonPath, err)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(sel.NamespaceSelector); err != nil {
+		return fmt.Errorf("%s.namespaceSelector is invalid: %w", jsonPath, err)
+	}
+	for _, ns := range sel.ExcludeNamespaces {
+		if ns == "" {
+			return fmt.Errorf("%s.excludeNamespaces cannot contain empty string", jsonPath)
+		}
+	}
+	return nil
+}
+
+func validateReoptimizeInterval(d *metav1.Duration, jsonPath string) error {
+	if d != nil && d.Duration < 0 {
+		return fmt.Errorf("%s must not be negative", jsonPath)
+	}
+	return nil
+}
+
+func validateReplicaBounds(bounds map[string]ReplicaBounds, jsonPath string) error {
+	for k, v := range bounds {
+		if k == "" {
+			return fmt.Errorf("%s cannot use empty string as key", jsonPath)
+		}
+		if v.MinReplicas < 0 {
+			return fmt.Errorf("%s[%s].minReplicas must not be negative", jsonPath, k)
+		}
+		if v.MaxReplicas != nil && *v.MaxReplicas < v.MinReplicas {
+			return fmt.Errorf("%s[%s].maxReplicas must not be less than minReplicas", jsonPath, k)
+		}
+	}
+	return nil
+}
+
+func validateDefaultPlacement(dp *DefaultPlacement, jsonPath string) error {
+	if dp == nil {
+		return nil
+	}
+	switch dp.Type {
+	case DefaultPlacementNone, DefaultPlacementAllClusters:
+		if dp.ClusterSelector != nil {
+			return fmt.Errorf("%s.clusterSelector cannot be specified with type %s", jsonPath, dp.Type)
+		}
+	case DefaultPlacementClusterSelector:
+		if dp.ClusterSelector == nil {
+			return fmt.Errorf("%s.clusterSelector is required with type %s", jsonPath, dp.Type)
+		}
+		if _, err := metav1.LabelSelectorAsSelector(dp.ClusterSelector); err != nil {
+			return fmt.Errorf("%s.clusterSelector is invalid: %w", jsonPath, err)
+		}
+	default:
+		return fmt.Errorf("invalid %s.type %s", jsonPath, dp.Type)
+	}
+	return nil
+}
+
+func validateCarbonIntensitySource(settings *RSPOptimizerSettings, jsonPath string) error {
+	src := settings.CarbonIntensity
+	if src == nil {
+		return fmt.Errorf("%s.carbonIntensity is required", jsonPath)
+	}
+	n := 0
+	if src.Static != nil {
+		n++
+		for k, v := range src.Static {
+			if k == "" {
+				return fmt.Errorf("%s.carbonIntensity.static cannot use empty string as key", jsonPath)
+			}
+			if v < 0 {
+				return fmt.Errorf("%s.carbonIntensity.static[%s] must not be negative", jsonPath, k)
+			}
+		}
+	}
+	if src.ConfigMap != nil {
+		n++
+		if src.ConfigMap.Namespace == "" || src.ConfigMap.Name == "" {
+			return fmt.Errorf("%s.carbonIntensity.configMap requires namespace and name", jsonPath)
+		}
+	}
+	if src.Endpoint != "" {
+		n++
+		if _, err := url.ParseRequestURI(src.Endpoint); err != nil {
+			return fmt.Errorf("%s.carbonIntensity.endpoint is not a valid URL: %w", jsonPath, err)
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("%s.carbonIntensity requires exactly one of static, configMap and endpoint", jsonPath)
+	}
+	if src.UseWAOEstimators {
+		return validateWAOEstimatorSources(settings.WAOEstimators, settings.WAOEstimatorDiscovery, jsonPath)
+	}
+	return nil
+}
+
+func validateTariffs(tariffs map[string]TariffSchedule, jsonPath string) error {
+	if len(tariffs) == 0 {
+		return fmt.Errorf("%s requires 1 or more items", jsonPath)
+	}
+	for k, v := range tariffs {
+		if k == "" {
+			return fmt.Errorf("%s cannot use empty string as key", jsonPath)
+		}
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("%s[%s] is invalid: %w", jsonPath, k, err)
+		}
+	}
+	return nil
+}
+
+func validateOptimizerWebhook(wh *OptimizerWebhook, jsonPath string) error {
+	if wh == nil {
+		return fmt.Errorf("%s is required", jsonPath)
+	}
+	u, err := url.ParseRequestURI(wh.URL)
+	if err != nil {
+		return fmt.Errorf("%s.url is not a valid URL: %w", jsonPath, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s.url must be http or https", jsonPath)
+	}
+	if len(wh.CABundle) > 0 && !x509.NewCertPool().AppendCertsFromPEM(wh.CABundle) {
+		return fmt.Errorf("%s.caBundle contains no valid PEM encoded certificates", jsonPath)
+	}
+	if wh.Timeout != nil && wh.Timeout.Duration <= 0 {
+		return fmt.Errorf("%s.timeout must be positive", jsonPath)
+	}
+	return nil
+}
+
+func validateRSPStabilization(st *RSPStabilization, jsonPath string) error {
+	if st == nil {
+		return nil
+	}
+	if st.Window != nil && st.Window.Duration < 0 {
+		return fmt.Errorf("%s.window must not be negative", jsonPath)
+	}
+	if st.MinImprovement != "" {
+		v, err := strconv.ParseFloat(st.MinImprovement, 64)
+		if err != nil {
+			return fmt.Errorf("%s.minImprovement is invalid: %w", jsonPath, err)
+		}
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s.minImprovement must be a non-negative number", jsonPath)
+		}
 	}
 	return nil
 }
 
 func (r *WAOFedConfig) validateScheduling() error {
+	// NOTE: the defaulting webhook ensures selector != nil
+	if err := validateResourceSelector(r.Spec.Scheduling.Selector, "spec.scheduling.selector"); err != nil {
+		return err
+	}
+	if err := validateReoptimizeInterval(r.Spec.Scheduling.Optimizer.ReoptimizeInterval, "spec.scheduling.optimizer.reoptimizeInterval"); err != nil {
+		return err
+	}
+	if err := validateReplicaBounds(r.Spec.Scheduling.Optimizer.ReplicaBounds, "spec.scheduling.optimizer.replicaBounds"); err != nil {
+		return err
+	}
+	if err := validateDefaultPlacement(r.Spec.Scheduling.Optimizer.DefaultPlacement, "spec.scheduling.optimizer.defaultPlacement"); err != nil {
+		return err
+	}
+	if err := validateRSPStabilization(r.Spec.Scheduling.Optimizer.Stabilization, "spec.scheduling.optimizer.stabilization"); err != nil {
+		return err
+	}
 	// NOTE: the defaulting webhook ensures method != nil
-	switch *r.Spec.Scheduling.Optimizer.Method {
+	if err := validateRSPOptimizerMethod(*r.Spec.Scheduling.Optimizer.Method, r.Spec.Scheduling.Optimizer, "spec.scheduling.optimizer", "method"); err != nil {
+		return err
+	}
+	for i, m := range r.Spec.Scheduling.Optimizer.FallbackMethods {
+		if err := validateRSPOptimizerMethod(m, r.Spec.Scheduling.Optimizer, "spec.scheduling.optimizer", fmt.Sprintf("fallbackMethods[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRSPOptimizerMethod validates the method and the settings it requires.
+// jsonPath is the path of the settings and field is the name of the field that specifies the method.
+func validateRSPOptimizerMethod(method RSPOptimizerMethod, settings *RSPOptimizerSettings, jsonPath, field string) error {
+	switch method {
 	case RSPOptimizerMethodRoundRobin:
 	case RSPOptimizerMethodWAO:
-		return validateWAOEstimators(r.Spec.Scheduling.Optimizer.WAOEstimators, "spec.scheduling.optimizer.waoEstimators")
+		return validateWAOEstimatorSources(settings.WAOEstimators, settings.WAOEstimatorDiscovery, jsonPath)
+	case RSPOptimizerMethodCapacity:
+	case RSPOptimizerMethodCarbon:
+		return validateCarbonIntensitySource(settings, jsonPath)
+	case RSPOptimizerMethodPrice:
+		if err := validateTariffs(settings.Tariffs, jsonPath+".tariffs"); err != nil {
+			return err
+		}
+		return validateWAOEstimatorSources(settings.WAOEstimators, settings.WAOEstimatorDiscovery, jsonPath)
+	case RSPOptimizerMethodWebhook:
+		return validateOptimizerWebhook(settings.Webhook, jsonPath+".webhook")
 	default:
-		return fmt.Errorf("invalid spec.scheduling.optimizer.method %s", *r.Spec.Scheduling.Optimizer.Method)
+		return fmt.Errorf("invalid %s.%s %s", jsonPath, field, method)
 	}
 	return nil
 }
 
 func (r *WAOFedConfig) validateLoadbalancing() error {
+	// NOTE: the defaulting webhook ensures selector != nil
+	if err := validateResourceSelector(r.Spec.LoadBalancing.Selector, "spec.loadbalancing.selector"); err != nil {
+		return err
+	}
+	if err := validateReoptimizeInterval(r.Spec.LoadBalancing.Optimizer.ReoptimizeInterval, "spec.loadbalancing.optimizer.reoptimizeInterval"); err != nil {
+		return err
+	}
 	// NOTE: the defaulting webhook ensures method != nil
-	switch *r.Spec.LoadBalancing.Optimizer.Method {
+	if err := validateSLPOptimizerMethod(*r.Spec.LoadBalancing.Optimizer.Method, r.Spec.LoadBalancing.Optimizer, "spec.loadbalancing.optimizer", "method"); err != nil {
+		return err
+	}
+	for i, m := range r.Spec.LoadBalancing.Optimizer.FallbackMethods {
+		if err := validateSLPOptimizerMethod(m, r.Spec.LoadBalancing.Optimizer, "spec.loadbalancing.optimizer", fmt.Sprintf("fallbackMethods[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSLPOptimizerMethod validates the method and the settings it requires.
+// jsonPath is the path of the settings and field is the name of the field that specifies the method.
+func validateSLPOptimizerMethod(method SLPOptimizerMethod, settings *SLPOptimizerSettings, jsonPath, field string) error {
+	switch method {
 	case SLPOptimizerMethodRoundRobin:
 	case SLPOptimizerMethodWAO:
-		return validateWAOEstimators(r.Spec.LoadBalancing.Optimizer.WAOEstimators, "spec.loadbalancing.optimizer.waoEstimators")
+		return validateWAOEstimatorSources(settings.WAOEstimators, settings.WAOEstimatorDiscovery, jsonPath)
+	case SLPOptimizerMethodWebhook:
+		return validateOptimizerWebhook(settings.Webhook, jsonPath+".webhook")
 	default:
-		return fmt.Errorf("invalid spec.loadbalancing.optimizer.method %s", *r.Spec.LoadBalancing.Optimizer.Method)
+		return fmt.Errorf("invalid %s.%s %s", jsonPath, field, method)
 	}
 	return nil
 }
@@ -127,15 +127,21 @@ var _ = Describe("WAOFedConfig webhook", func() {
 			testMutate(mustOpen("testdata", "mutate_all_before.yaml"), mustOpen("testdata", "mutate_all_after.yaml"))
 			testMutate(mustOpen("testdata", "mutate_scheduling_before.yaml"), mustOpen("testdata", "mutate_scheduling_after.yaml"))
 			testMutate(mustOpen("testdata", "mutate_loadbalancing_before.yaml"), mustOpen("testdata", "mutate_loadbalancing_after.yaml"))
+			testMutate(mustOpen("testdata", "mutate_webhook_before.yaml"), mustOpen("testdata", "mutate_webhook_after.yaml"))
 			testMutate(mustOpen("testdata", "rspwao", "mutate_before.yaml"), mustOpen("testdata", "rspwao", "mutate_after.yaml"))
+			testMutate(mustOpen("testdata", "rspwao", "mutate_discovery_before.yaml"), mustOpen("testdata", "rspwao", "mutate_discovery_after.yaml"))
 		})
 	})
 	Context("validating", func() {
 		It("should create resources", func() {
 			want := true
 			testValidate(mustOpen("testdata", "validate_all.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_carbon.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_price.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_webhook.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_1cluster.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_3clusters.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_discovery.yaml"), want)
 			_ = want
 		})
 		It("should not create resources", func() {
@@ -144,10 +150,25 @@ var _ = Describe("WAOFedConfig webhook", func() {
 			testValidate(mustOpen("testdata", "validate_invalid_kubefedns.yaml"), want)
 			testValidate(mustOpen("testdata", "validate_invalid_rspoptimizermethod.yaml"), want)
 			testValidate(mustOpen("testdata", "validate_invalid_slpoptimizermethod.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_reoptimizeinterval.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_selector.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_replicabounds.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_carbon.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_price.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_webhook.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_fallbackmethods.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_stabilization.yaml"), want)
+			testValidate(mustOpen("testdata", "validate_invalid_defaultplacement.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_no_estimators.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_no_clusters.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_cluster_name.yaml"), want)
 			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_url.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_rate_limit.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_retry.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_circuit_breaker.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_auth_http.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_auth_both.yaml"), want)
+			testValidate(mustOpen("testdata", "rspwao", "validate_invalid_discovery.yaml"), want)
 			_ = want
 		})
 	})
@@ -6,9 +6,38 @@
 package v1beta1
 
 import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CarbonIntensitySource) DeepCopyInto(out *CarbonIntensitySource) {
+	*out = *in
+	if in.Static != nil {
+		in, out := &in.Static, &out.Static
+		*out = make(map[string]int64, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ConfigMapReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CarbonIntensitySource.
+func (in *CarbonIntensitySource) DeepCopy() *CarbonIntensitySource {
+	if in == nil {
+		return nil
+	}
+	out := new(CarbonIntensitySource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterPreferences) DeepCopyInto(out *ClusterPreferences) {
 	*out = *in
@@ -24,6 +53,97 @@ func (in *ClusterPreferences) DeepCopy() *ClusterPreferences {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
+	*out = *in
+	if in.SchedulingEstimator != nil {
+		in, out := &in.SchedulingEstimator, &out.SchedulingEstimator
+		*out = new(EstimatorStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LoadBalancingEstimator != nil {
+		in, out := &in.LoadBalancingEstimator, &out.LoadBalancingEstimator
+		*out = new(EstimatorStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
+func (in *ClusterStatus) DeepCopy() *ClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapReference) DeepCopyInto(out *ConfigMapReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapReference.
+func (in *ConfigMapReference) DeepCopy() *ConfigMapReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultPlacement) DeepCopyInto(out *DefaultPlacement) {
+	*out = *in
+	if in.ClusterSelector != nil {
+		in, out := &in.ClusterSelector, &out.ClusterSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultPlacement.
+func (in *DefaultPlacement) DeepCopy() *DefaultPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EstimatorStatus) DeepCopyInto(out *EstimatorStatus) {
+	*out = *in
+	in.LastProbeTime.DeepCopyInto(&out.LastProbeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EstimatorStatus.
+func (in *EstimatorStatus) DeepCopy() *EstimatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EstimatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederatedObjectValidator) DeepCopyInto(out *FederatedObjectValidator) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederatedObjectValidator.
+func (in *FederatedObjectValidator) DeepCopy() *FederatedObjectValidator {
+	if in == nil {
+		return nil
+	}
+	out := new(FederatedObjectValidator)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadBalancingSettings) DeepCopyInto(out *LoadBalancingSettings) {
 	*out = *in
@@ -49,6 +169,103 @@ func (in *LoadBalancingSettings) DeepCopy() *LoadBalancingSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeClusterPreferences) DeepCopyInto(out *OptimizeClusterPreferences) {
+	*out = *in
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeClusterPreferences.
+func (in *OptimizeClusterPreferences) DeepCopy() *OptimizeClusterPreferences {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeClusterPreferences)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeRequest) DeepCopyInto(out *OptimizeRequest) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Object.DeepCopyInto(&out.Object)
+	if in.Requests != nil {
+		in, out := &in.Requests, &out.Requests
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeRequest.
+func (in *OptimizeRequest) DeepCopy() *OptimizeRequest {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeRequest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeResponse) DeepCopyInto(out *OptimizeResponse) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make(map[string]OptimizeClusterPreferences, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeResponse.
+func (in *OptimizeResponse) DeepCopy() *OptimizeResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizerWebhook) DeepCopyInto(out *OptimizerWebhook) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizerWebhook.
+func (in *OptimizerWebhook) DeepCopy() *OptimizerWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizerWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RSPOptimizerSettings) DeepCopyInto(out *RSPOptimizerSettings) {
 	*out = *in
@@ -67,11 +284,70 @@ func (in *RSPOptimizerSettings) DeepCopyInto(out *RSPOptimizerSettings) {
 			} else {
 				in, out := &val, &outVal
 				*out = new(WAOEstimatorSetting)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 			(*out)[key] = outVal
 		}
 	}
+	if in.WAOEstimatorDiscovery != nil {
+		in, out := &in.WAOEstimatorDiscovery, &out.WAOEstimatorDiscovery
+		*out = new(WAOEstimatorDiscovery)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReoptimizeInterval != nil {
+		in, out := &in.ReoptimizeInterval, &out.ReoptimizeInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ReplicaBounds != nil {
+		in, out := &in.ReplicaBounds, &out.ReplicaBounds
+		*out = make(map[string]ReplicaBounds, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.CarbonIntensity != nil {
+		in, out := &in.CarbonIntensity, &out.CarbonIntensity
+		*out = new(CarbonIntensitySource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tariffs != nil {
+		in, out := &in.Tariffs, &out.Tariffs
+		*out = make(map[string]TariffSchedule, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(OptimizerWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Stabilization != nil {
+		in, out := &in.Stabilization, &out.Stabilization
+		*out = new(RSPStabilization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DefaultPlacement != nil {
+		in, out := &in.DefaultPlacement, &out.DefaultPlacement
+		*out = new(DefaultPlacement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rebalance != nil {
+		in, out := &in.Rebalance, &out.Rebalance
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IntersectWithClusterSelector != nil {
+		in, out := &in.IntersectWithClusterSelector, &out.IntersectWithClusterSelector
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FallbackMethods != nil {
+		in, out := &in.FallbackMethods, &out.FallbackMethods
+		*out = make([]RSPOptimizerMethod, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RSPOptimizerSettings.
@@ -84,6 +360,46 @@ func (in *RSPOptimizerSettings) DeepCopy() *RSPOptimizerSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RSPStabilization) DeepCopyInto(out *RSPStabilization) {
+	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RSPStabilization.
+func (in *RSPStabilization) DeepCopy() *RSPStabilization {
+	if in == nil {
+		return nil
+	}
+	out := new(RSPStabilization)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaBounds) DeepCopyInto(out *ReplicaBounds) {
+	*out = *in
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaBounds.
+func (in *ReplicaBounds) DeepCopy() *ReplicaBounds {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaBounds)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
 	*out = *in
@@ -97,6 +413,21 @@ func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSelector.
@@ -109,6 +440,22 @@ func (in *ResourceSelector) DeepCopy() *ResourceSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLPConsumerStatus) DeepCopyInto(out *SLPConsumerStatus) {
+	*out = *in
+	in.LastAppliedTime.DeepCopyInto(&out.LastAppliedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLPConsumerStatus.
+func (in *SLPConsumerStatus) DeepCopy() *SLPConsumerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SLPConsumerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SLPOptimizerSettings) DeepCopyInto(out *SLPOptimizerSettings) {
 	*out = *in
@@ -127,11 +474,31 @@ func (in *SLPOptimizerSettings) DeepCopyInto(out *SLPOptimizerSettings) {
 			} else {
 				in, out := &val, &outVal
 				*out = new(WAOEstimatorSetting)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 			(*out)[key] = outVal
 		}
 	}
+	if in.WAOEstimatorDiscovery != nil {
+		in, out := &in.WAOEstimatorDiscovery, &out.WAOEstimatorDiscovery
+		*out = new(WAOEstimatorDiscovery)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReoptimizeInterval != nil {
+		in, out := &in.ReoptimizeInterval, &out.ReoptimizeInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(OptimizerWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FallbackMethods != nil {
+		in, out := &in.FallbackMethods, &out.FallbackMethods
+		*out = make([]SLPOptimizerMethod, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLPOptimizerSettings.
@@ -144,6 +511,29 @@ func (in *SLPOptimizerSettings) DeepCopy() *SLPOptimizerSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SLPOptimizerStatus) DeepCopyInto(out *SLPOptimizerStatus) {
+	*out = *in
+	in.LastOptimizedTime.DeepCopyInto(&out.LastOptimizedTime)
+	if in.ExcludedClusters != nil {
+		in, out := &in.ExcludedClusters, &out.ExcludedClusters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SLPOptimizerStatus.
+func (in *SLPOptimizerStatus) DeepCopy() *SLPOptimizerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SLPOptimizerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SchedulingSettings) DeepCopyInto(out *SchedulingSettings) {
 	*out = *in
@@ -169,13 +559,28 @@ func (in *SchedulingSettings) DeepCopy() *SchedulingSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceLoadbalancingPreference) DeepCopyInto(out *ServiceLoadbalancingPreference) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceLoadbalancingPreference.
@@ -253,6 +658,16 @@ func (in *ServiceLoadbalancingPreferenceSpec) DeepCopy() *ServiceLoadbalancingPr
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceLoadbalancingPreferenceStatus) DeepCopyInto(out *ServiceLoadbalancingPreferenceStatus) {
 	*out = *in
+	if in.Optimizer != nil {
+		in, out := &in.Optimizer, &out.Optimizer
+		*out = new(SLPOptimizerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Consumer != nil {
+		in, out := &in.Consumer, &out.Consumer
+		*out = new(SLPConsumerStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceLoadbalancingPreferenceStatus.
@@ -265,9 +680,184 @@ func (in *ServiceLoadbalancingPreferenceStatus) DeepCopy() *ServiceLoadbalancing
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TariffBand) DeepCopyInto(out *TariffBand) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TariffBand.
+func (in *TariffBand) DeepCopy() *TariffBand {
+	if in == nil {
+		return nil
+	}
+	out := new(TariffBand)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TariffSchedule) DeepCopyInto(out *TariffSchedule) {
+	*out = *in
+	if in.Bands != nil {
+		in, out := &in.Bands, &out.Bands
+		*out = make([]TariffBand, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TariffSchedule.
+func (in *TariffSchedule) DeepCopy() *TariffSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(TariffSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WAOEstimatorCircuitBreaker) DeepCopyInto(out *WAOEstimatorCircuitBreaker) {
+	*out = *in
+	if in.OpenDuration != nil {
+		in, out := &in.OpenDuration, &out.OpenDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorCircuitBreaker.
+func (in *WAOEstimatorCircuitBreaker) DeepCopy() *WAOEstimatorCircuitBreaker {
+	if in == nil {
+		return nil
+	}
+	out := new(WAOEstimatorCircuitBreaker)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WAOEstimatorDiscovery) DeepCopyInto(out *WAOEstimatorDiscovery) {
+	*out = *in
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(WAOEstimatorServiceReference)
+		**out = **in
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(WAOEstimatorSetting)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorDiscovery.
+func (in *WAOEstimatorDiscovery) DeepCopy() *WAOEstimatorDiscovery {
+	if in == nil {
+		return nil
+	}
+	out := new(WAOEstimatorDiscovery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WAOEstimatorRateLimit) DeepCopyInto(out *WAOEstimatorRateLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorRateLimit.
+func (in *WAOEstimatorRateLimit) DeepCopy() *WAOEstimatorRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(WAOEstimatorRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WAOEstimatorRetry) DeepCopyInto(out *WAOEstimatorRetry) {
+	*out = *in
+	if in.Backoff != nil {
+		in, out := &in.Backoff, &out.Backoff
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorRetry.
+func (in *WAOEstimatorRetry) DeepCopy() *WAOEstimatorRetry {
+	if in == nil {
+		return nil
+	}
+	out := new(WAOEstimatorRetry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WAOEstimatorServiceReference) DeepCopyInto(out *WAOEstimatorServiceReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorServiceReference.
+func (in *WAOEstimatorServiceReference) DeepCopy() *WAOEstimatorServiceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(WAOEstimatorServiceReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WAOEstimatorSetting) DeepCopyInto(out *WAOEstimatorSetting) {
 	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClientCertSecret != nil {
+		in, out := &in.ClientCertSecret, &out.ClientCertSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.BearerTokenSecret != nil {
+		in, out := &in.BearerTokenSecret, &out.BearerTokenSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.BasicAuthSecret != nil {
+		in, out := &in.BasicAuthSecret, &out.BasicAuthSecret
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.CacheTTL != nil {
+		in, out := &in.CacheTTL, &out.CacheTTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(WAOEstimatorRateLimit)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Retry != nil {
+		in, out := &in.Retry, &out.Retry
+		*out = new(WAOEstimatorRetry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CircuitBreaker != nil {
+		in, out := &in.CircuitBreaker, &out.CircuitBreaker
+		*out = new(WAOEstimatorCircuitBreaker)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOEstimatorSetting.
@@ -286,7 +876,7 @@ func (in *WAOFedConfig) DeepCopyInto(out *WAOFedConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOFedConfig.
@@ -367,6 +957,20 @@ func (in *WAOFedConfigSpec) DeepCopy() *WAOFedConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WAOFedConfigStatus) DeepCopyInto(out *WAOFedConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Clusters != nil {
+		in, out := &in.Clusters, &out.Clusters
+		*out = make([]ClusterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WAOFedConfigStatus.
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	fedctrlutil "sigs.k8s.io/kubefed/pkg/controller/util"
+)
+
+// memberClusterTimeout is the timeout for requests to member clusters.
+const memberClusterTimeout = 10 * time.Second
+
+// memberClusterClientsets caches clientsets for member clusters per KubeFedCluster,
+// so that connections are reused across reconciliations.
+type memberClusterClientsets struct {
+	mu sync.Mutex
+	m  map[client.ObjectKey]*memberClusterClientset
+}
+
+// memberClusterClientset is a clientset built with the config identified by fingerprint,
+// so that it is rebuilt when the KubeFedCluster or its Secret is changed.
+type memberClusterClientset struct {
+	clientset   kubernetes.Interface
+	fingerprint string
+}
+
+func newMemberClusterClientsets() *memberClusterClientsets {
+	return &memberClusterClientsets{m: map[client.ObjectKey]*memberClusterClientset{}}
+}
+
+// defaultMemberClusterClientsets is shared by all controllers in the manager.
+var defaultMemberClusterClientsets = newMemberClusterClientsets()
+
+// get returns the cached clientset for the member cluster registered as the KubeFedCluster,
+// or a new one if not cached or the config is changed.
+func (p *memberClusterClientsets) get(ctx context.Context, c client.Reader, kubefedNamespace, cluster string) (kubernetes.Interface, error) {
+	cfg, err := newMemberClusterConfig(ctx, c, kubefedNamespace, cluster)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "host=%s\ninsecure=%t\ntoken=%s\n", cfg.Host, cfg.Insecure, cfg.BearerToken)
+	h.Write(cfg.CAData)
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	key := client.ObjectKey{Namespace: kubefedNamespace, Name: cluster}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cached, ok := p.m[key]; ok && cached.fingerprint == fingerprint {
+		return cached.clientset, nil
+	}
+	cs, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p.m[key] = &memberClusterClientset{clientset: cs, fingerprint: fingerprint}
+	return cs, nil
+}
+
+// newMemberClusterConfig returns a config for the member cluster registered as the KubeFedCluster.
+//
+// Ref. sigs.k8s.io/kubefed/pkg/controller/util.BuildClusterConfig
+func newMemberClusterConfig(ctx context.Context, c client.Reader, kubefedNamespace, cluster string) (*rest.Config, error) {
+	kfc := &fedcorev1b1.KubeFedCluster{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: kubefedNamespace, Name: cluster}, kfc); err != nil {
+		return nil, err
+	}
+	if kfc.Spec.APIEndpoint == "" {
+		return nil, fmt.Errorf("the api endpoint of cluster %s is empty", cluster)
+	}
+	if kfc.Spec.SecretRef.Name == "" {
+		return nil, fmt.Errorf("cluster %s does not have a secret name", cluster)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: kubefedNamespace, Name: kfc.Spec.SecretRef.Name}, secret); err != nil {
+		return nil, err
+	}
+	token := secret.Data[fedctrlutil.TokenKey]
+	if len(token) == 0 {
+		return nil, fmt.Errorf("the secret for cluster %s is missing a non-empty value for %q", cluster, fedctrlutil.TokenKey)
+	}
+
+	cfg := &rest.Config{
+		Host:        kfc.Spec.APIEndpoint,
+		BearerToken: string(token),
+		Timeout:     memberClusterTimeout,
+	}
+	cfg.CAData = kfc.Spec.CABundle
+	for _, v := range kfc.Spec.DisabledTLSValidations {
+		if v == fedcorev1b1.TLSAll {
+			cfg.Insecure = true
+			cfg.CAData = nil
+		}
+	}
+	return cfg, nil
+}
+
+// countSchedulablePodsInCluster returns the number of pods with the given requests that can be scheduled on the member cluster.
+func countSchedulablePodsInCluster(ctx context.Context, c client.Reader, kubefedNamespace, cluster string, requests corev1.ResourceList) (int64, error) {
+	cs, err := defaultMemberClusterClientsets.get(ctx, c, kubefedNamespace, cluster)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	pods, err := cs.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		// only the pods bound to nodes and not terminated use the resources
+		FieldSelector: fields.AndSelectors(
+			fields.OneTermNotEqualSelector("spec.nodeName", ""),
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodSucceeded)),
+			fields.OneTermNotEqualSelector("status.phase", string(corev1.PodFailed)),
+		).String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return countSchedulablePods(nodes.Items, pods.Items, requests), nil
+}
+
+// countSchedulablePods returns the number of pods with the given requests that can be scheduled on the nodes,
+// where each node has allocatable resources minus the requests of the running pods.
+// Unschedulable or not ready nodes are ignored.
+//
+// NOTE: only cpu and memory are considered, and a pod requests at least 1m cpu.
+func countSchedulablePods(nodes []corev1.Node, pods []corev1.Pod, requests corev1.ResourceList) int64 {
+	used := map[string]corev1.ResourceList{}
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		addResourceList(used, pod.Spec.NodeName, podResources(&pod.Spec, false))
+	}
+
+	cpuReq := requests.Cpu().MilliValue()
+	if cpuReq < 1 {
+		cpuReq = 1
+	}
+	memReq := requests.Memory().Value()
+
+	var total int64
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Spec.Unschedulable || !isNodeReady(node) {
+			continue
+		}
+		u := used[node.Name]
+		freeCPU := node.Status.Allocatable.Cpu().MilliValue() - u.Cpu().MilliValue()
+		freeMem := node.Status.Allocatable.Memory().Value() - u.Memory().Value()
+		if freeCPU <= 0 || freeMem <= 0 {
+			continue
+		}
+		n := freeCPU / cpuReq
+		if memReq > 0 && freeMem/memReq < n {
+			n = freeMem / memReq
+		}
+		total += n
+	}
+	return total
+}
+
+func addResourceList(m map[string]corev1.ResourceList, key string, rl corev1.ResourceList) {
+	if _, ok := m[key]; !ok {
+		m[key] = corev1.ResourceList{}
+	}
+	for name, q := range rl {
+		cur := m[key][name]
+		cur.Add(q)
+		m[key][name] = cur
+	}
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
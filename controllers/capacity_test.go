@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func helperResourceList(cpu, memory string) corev1.ResourceList {
+	rl := corev1.ResourceList{}
+	if cpu != "" {
+		rl[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		rl[corev1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return rl
+}
+
+func helperNode(name, cpu, memory string, ready, unschedulable bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Allocatable: helperResourceList(cpu, memory),
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func helperPod(node, cpu, memory string, phase corev1.PodPhase) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: helperResourceList(cpu, memory)}}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func Test_countSchedulablePods(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    []corev1.Node
+		pods     []corev1.Pod
+		requests corev1.ResourceList
+		want     int64
+	}{
+		{"empty", nil, nil, helperResourceList("100m", "100Mi"), 0},
+		{"cpu_bound", []corev1.Node{
+			helperNode("n1", "1", "8Gi", true, false),
+		}, nil, helperResourceList("300m", "100Mi"), 3},
+		{"memory_bound", []corev1.Node{
+			helperNode("n1", "4", "1Gi", true, false),
+		}, nil, helperResourceList("100m", "300Mi"), 3},
+		{"per_node", []corev1.Node{
+			helperNode("n1", "500m", "8Gi", true, false),
+			helperNode("n2", "500m", "8Gi", true, false),
+		}, nil, helperResourceList("300m", ""), 2},
+		{"running_pods", []corev1.Node{
+			helperNode("n1", "1", "8Gi", true, false),
+			helperNode("n2", "1", "8Gi", true, false),
+		}, []corev1.Pod{
+			helperPod("n1", "500m", "", corev1.PodRunning),
+			helperPod("n2", "2", "", corev1.PodRunning),
+			helperPod("n2", "1", "", corev1.PodSucceeded),
+			helperPod("", "1", "", corev1.PodPending),
+		}, helperResourceList("100m", ""), 5},
+		{"not_ready_or_unschedulable", []corev1.Node{
+			helperNode("n1", "1", "8Gi", false, false),
+			helperNode("n2", "1", "8Gi", true, true),
+			helperNode("n3", "1", "8Gi", true, false),
+		}, nil, helperResourceList("500m", ""), 2},
+		{"no_requests", []corev1.Node{
+			helperNode("n1", "1", "8Gi", true, false),
+		}, nil, corev1.ResourceList{}, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := countSchedulablePods(tt.nodes, tt.pods, tt.requests)
+			if got != tt.want {
+				t.Errorf("countSchedulablePods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// helperMemberClusterServer returns a fake API server of a member cluster that serves the nodes and the pods.
+func helperMemberClusterServer(t *testing.T, nodes []corev1.Node, pods []corev1.Pod) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			_ = json.NewEncoder(w).Encode(&corev1.NodeList{Items: nodes})
+		case "/api/v1/pods":
+			if got, want := r.URL.Query().Get("fieldSelector"), "spec.nodeName!=,status.phase!=Succeeded,status.phase!=Failed"; got != want {
+				t.Errorf("fieldSelector = %s, want %s", got, want)
+			}
+			_ = json.NewEncoder(w).Encode(&corev1.PodList{Items: pods})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func helperMemberKubeFedCluster(name, endpoint string) (*fedcorev1b1.KubeFedCluster, *corev1.Secret) {
+	kfc := &fedcorev1b1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-federation-system", Name: name},
+		Spec: fedcorev1b1.KubeFedClusterSpec{
+			APIEndpoint: endpoint,
+			SecretRef:   fedcorev1b1.LocalSecretReference{Name: name + "-token"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-federation-system", Name: name + "-token"},
+		Data:       map[string][]byte{"token": []byte("t0ken")},
+	}
+	return kfc, secret
+}
+
+func Test_rspOptimizeFnCapacity(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = fedcorev1b1.AddToScheme(scheme)
+	free := helperMemberClusterServer(t, []corev1.Node{helperNode("n1", "4", "8Gi", true, false)}, nil)
+	full := helperMemberClusterServer(t, []corev1.Node{helperNode("n1", "4", "8Gi", true, false)},
+		[]corev1.Pod{helperPod("n1", "4", "", corev1.PodRunning)})
+	c1, s1 := helperMemberKubeFedCluster("c1", free.URL)
+	c2, s2 := helperMemberKubeFedCluster("c2", full.URL)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(c1, s1, c2, s2).Build()
+	fdeploy := helperFederatedDeploymentWithCPU(pointer.Int32(1), "1")
+
+	tests := []struct {
+		name     string
+		clusters []string
+		want     map[string]fedschedv1a1.ClusterPreferences
+		wantErr  bool
+	}{
+		{"measured", []string{"c1", "c2", "c3"}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {Weight: 4}, "c2": {Weight: 0}, "c3": {Weight: 0},
+		}, false},
+		{"measured_zero", []string{"c2", "c3"}, map[string]fedschedv1a1.ClusterPreferences{
+			"c2": {Weight: 1}, "c3": {Weight: 0},
+		}, false},
+		{"not_measured", []string{"c3"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := rspOptimizeFnCapacity(context.Background(), c, "kube-federation-system", tt.clusters, &v1beta1.RSPOptimizerSettings{}, fdeploy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rspOptimizeFnCapacity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(res.clusters, tt.want); diff != "" {
+				t.Errorf("rspOptimizeFnCapacity() = %v, want %v, diff %s", res.clusters, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_memberClusterClientsets_get(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = fedcorev1b1.AddToScheme(scheme)
+	kfc, secret := helperMemberKubeFedCluster("c1", "https://c1:6443")
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(kfc, secret).Build()
+	ctx := context.Background()
+	p := newMemberClusterClientsets()
+
+	cs1, err := p.get(ctx, c, "kube-federation-system", "c1")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	cs2, err := p.get(ctx, c, "kube-federation-system", "c1")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if cs1 != cs2 {
+		t.Errorf("get() returned a new clientset, want the cached one")
+	}
+
+	// rotate the token
+	secret.Data["token"] = []byte("n3w")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	cs3, err := p.get(ctx, c, "kube-federation-system", "c1")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if cs3 == cs1 {
+		t.Errorf("get() returned the cached clientset, want a new one for the rotated token")
+	}
+
+	if _, err := p.get(ctx, c, "kube-federation-system", "c2"); err == nil {
+		t.Errorf("get() error = nil for an unregistered cluster")
+	}
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+const (
+	// carbonIntensityEndpointTimeout is the timeout for a request to the carbon intensity endpoint.
+	carbonIntensityEndpointTimeout = 10 * time.Second
+	// carbonIntensityEndpointMaxBytes is the maximum size of a response from the carbon intensity endpoint.
+	carbonIntensityEndpointMaxBytes = 1 << 20
+	// carbonMaxWeight is the weight of the cluster with the lowest carbon intensity.
+	carbonMaxWeight = 1000
+)
+
+// getCarbonIntensities returns the carbon intensity (gCO2/kWh) of each cluster from the source.
+// The returned slice has the same order as the given clusters.
+func getCarbonIntensities(ctx context.Context, c client.Reader, src *v1beta1.CarbonIntensitySource, clusters []string) ([]float64, error) {
+	table := map[string]float64{}
+	switch {
+	case src.Static != nil:
+		for k, v := range src.Static {
+			table[k] = float64(v)
+		}
+	case src.ConfigMap != nil:
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, client.ObjectKey{Namespace: src.ConfigMap.Namespace, Name: src.ConfigMap.Name}, cm); err != nil {
+			return nil, err
+		}
+		for k, v := range cm.Data {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("ConfigMap %s/%s data[%s]: %w", cm.Namespace, cm.Name, k, err)
+			}
+			table[k] = f
+		}
+	case src.Endpoint != "":
+		var err error
+		table, err = fetchCarbonIntensities(ctx, src.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("no carbon intensity source specified")
+	}
+	return resolveCarbonIntensities(table, clusters)
+}
+
+// fetchCarbonIntensities gets a JSON object with cluster names as keys and carbon intensities as values from the endpoint.
+func fetchCarbonIntensities(ctx context.Context, endpoint string) (map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, carbonIntensityEndpointTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %s", endpoint, resp.Status)
+	}
+
+	table := map[string]float64{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, carbonIntensityEndpointMaxBytes)).Decode(&table); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+	return table, nil
+}
+
+// resolveCarbonIntensities returns the carbon intensity of each cluster.
+// An explicit entry takes precedence over "*", and a cluster without entries causes an error.
+func resolveCarbonIntensities(table map[string]float64, clusters []string) ([]float64, error) {
+	out := make([]float64, len(clusters))
+	for i, c := range clusters {
+		v, ok := table[c]
+		if !ok {
+			v, ok = table["*"]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no carbon intensity for cluster %s", c)
+		}
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid carbon intensity %v for cluster %s", v, c)
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+// carbonWeights returns weights inversely proportional to the carbon intensities.
+// The cluster with the lowest carbon intensity gets carbonMaxWeight, and the others get at least 1.
+func carbonWeights(intensities []float64) []int64 {
+	min := math.Inf(1)
+	for _, v := range intensities {
+		min = math.Min(min, v)
+	}
+	weights := make([]int64, len(intensities))
+	for i, v := range intensities {
+		w := int64(carbonMaxWeight)
+		if v > 0 {
+			w = int64(math.Round(carbonMaxWeight * min / v))
+		}
+		if w < 1 {
+			w = 1
+		}
+		weights[i] = w
+	}
+	return weights
+}
+
+// scaleCosts returns a new matrix whose i-th row is costs[i] multiplied by factors[i].
+// +Inf (unavailable) is kept as is.
+func scaleCosts(costs [][]float64, factors []float64) [][]float64 {
+	out := make([][]float64, len(costs))
+	for i := range costs {
+		out[i] = make([]float64, len(costs[i]))
+		for j, v := range costs[i] {
+			if math.IsInf(v, 1) {
+				out[i][j] = v
+				continue
+			}
+			out[i][j] = v * factors[i]
+		}
+	}
+	return out
+}
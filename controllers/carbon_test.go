@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_getCarbonIntensities(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"c1": 300, "c2": 52.5}`))
+		case "/invalid":
+			_, _ = w.Write([]byte(`{"c1": "foo"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "carbon"},
+			Data:       map[string]string{"c1": "300", "*": "52.5"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "invalid"},
+			Data:       map[string]string{"c1": "foo"},
+		},
+	).Build()
+
+	clusters := []string{"c1", "c2"}
+	tests := []struct {
+		name    string
+		src     *v1beta1.CarbonIntensitySource
+		want    []float64
+		wantErr bool
+	}{
+		{"static", &v1beta1.CarbonIntensitySource{Static: map[string]int64{"c1": 300, "c2": 50}}, []float64{300, 50}, false},
+		{"static_wildcard", &v1beta1.CarbonIntensitySource{Static: map[string]int64{"c1": 300, "*": 50}}, []float64{300, 50}, false},
+		{"static_missing", &v1beta1.CarbonIntensitySource{Static: map[string]int64{"c1": 300}}, nil, true},
+		{"configmap", &v1beta1.CarbonIntensitySource{ConfigMap: &v1beta1.ConfigMapReference{Namespace: "default", Name: "carbon"}}, []float64{300, 52.5}, false},
+		{"configmap_invalid", &v1beta1.CarbonIntensitySource{ConfigMap: &v1beta1.ConfigMapReference{Namespace: "default", Name: "invalid"}}, nil, true},
+		{"configmap_not_found", &v1beta1.CarbonIntensitySource{ConfigMap: &v1beta1.ConfigMapReference{Namespace: "default", Name: "foo"}}, nil, true},
+		{"endpoint", &v1beta1.CarbonIntensitySource{Endpoint: srv.URL + "/ok"}, []float64{300, 52.5}, false},
+		{"endpoint_invalid", &v1beta1.CarbonIntensitySource{Endpoint: srv.URL + "/invalid"}, nil, true},
+		{"endpoint_not_found", &v1beta1.CarbonIntensitySource{Endpoint: srv.URL + "/foo"}, nil, true},
+		{"no_source", &v1beta1.CarbonIntensitySource{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getCarbonIntensities(context.Background(), c, tt.src, clusters)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getCarbonIntensities() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("getCarbonIntensities() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_carbonWeights(t *testing.T) {
+	tests := []struct {
+		name        string
+		intensities []float64
+		want        []int64
+	}{
+		{"empty", []float64{}, []int64{}},
+		{"inverse", []float64{100, 200, 400}, []int64{1000, 500, 250}},
+		{"same", []float64{300, 300}, []int64{1000, 1000}},
+		{"zero", []float64{0, 100}, []int64{1000, 1}},
+		{"at_least_1", []float64{1, 10000}, []int64{1000, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := carbonWeights(tt.intensities)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("carbonWeights() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_scaleCosts(t *testing.T) {
+	inf := math.Inf(1)
+	got := scaleCosts([][]float64{{1, 2}, {inf, inf}, {3, 4}}, []float64{10, 0, 0.5})
+	want := [][]float64{{10, 20}, {inf, inf}, {1.5, 2}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("scaleCosts() = %v, want %v, diff %s", got, want, diff)
+	}
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	fedctrlutil "sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// Reasons for excluding clusters from the candidates.
+const (
+	clusterExcludedNotRegistered = "NotRegistered"
+	clusterExcludedNotReady      = "NotReady"
+	clusterExcludedOffline       = "Offline"
+)
+
+// listCandidateClusters returns the clusters selected by the placement without duplicates,
+// and the clusters excluded as they are not registered as KubeFedClusters or not healthy, with the reasons.
+//
+// NOTE: the placement must have clusters or clusterSelector.
+func listCandidateClusters(
+	ctx context.Context, c client.Reader, placement *fedctrlutil.GenericPlacementFields, kubefedNamespace string,
+) ([]string, map[string]string, error) {
+	lg := log.FromContext(ctx)
+
+	// list clusters
+	//   if has placement.clusters field, the specified clusters should be candidates
+	//   if only has placement.clusterSelector field, the selected clusters should be candidates
+	var candidates []string
+	if placement.Clusters != nil {
+		// placement.clusters is specified
+		lg.Info("placement.clusters found", "spec.placement.clusters", placement.Clusters)
+		for _, c := range placement.Clusters {
+			candidates = append(candidates, c.Name)
+		}
+	} else { // placement.ClusterSelector != nil
+		// placement.clusterSelector is specified
+		lg.Info("placement.clusterSelector found", "spec.placement.clusterSelector", placement.ClusterSelector)
+		sel, err := metav1.LabelSelectorAsSelector(placement.ClusterSelector)
+		if err != nil {
+			lg.Error(err, "placement.clusterSelector")
+			return nil, nil, err
+		}
+		cl := &fedcorev1b1.KubeFedClusterList{}
+		if err := c.List(ctx, cl, &client.ListOptions{
+			Namespace:     kubefedNamespace,
+			LabelSelector: sel,
+		}); err != nil {
+			return nil, nil, err
+		}
+		for _, c := range cl.Items {
+			candidates = append(candidates, c.Name)
+		}
+	}
+
+	// filter candidates
+	// remove unregistered, unhealthy or duplicated clusters
+	// NOTE: FederatedDeployment spec.placement does not guarantee its validity
+	cl := &fedcorev1b1.KubeFedClusterList{}
+	if err := c.List(ctx, cl, &client.ListOptions{
+		Namespace: kubefedNamespace,
+	}); err != nil {
+		return nil, nil, err
+	}
+	registered := make(map[string]*fedcorev1b1.KubeFedCluster, len(cl.Items))
+	for i := range cl.Items {
+		registered[cl.Items[i].Name] = &cl.Items[i]
+	}
+
+	var clusters []string
+	excluded := map[string]string{}
+	dedup := map[string]int{}
+	for _, c := range candidates {
+		// deduplication
+		dedup[c] += 1
+		if dedup[c] != 1 {
+			continue
+		}
+		// check registration and health
+		kfc, ok := registered[c]
+		if !ok {
+			excluded[c] = clusterExcludedNotRegistered
+			continue
+		}
+		if reason := kubeFedClusterUnhealthyReason(kfc); reason != "" {
+			excluded[c] = reason
+			continue
+		}
+		clusters = append(clusters, c)
+	}
+	if len(excluded) > 0 {
+		lg.Info("excluded clusters", "excluded", excluded)
+	}
+	return clusters, excluded, nil
+}
+
+// kubeFedClusterUnhealthyReason returns the reason why the KubeFedCluster cannot accept workloads,
+// or an empty string if it is healthy.
+func kubeFedClusterUnhealthyReason(c *fedcorev1b1.KubeFedCluster) string {
+	for _, cond := range c.Status.Conditions {
+		if cond.Type == common.ClusterOffline && cond.Status == corev1.ConditionTrue {
+			return clusterExcludedOffline
+		}
+	}
+	if !isKubeFedClusterReady(c) {
+		return clusterExcludedNotReady
+	}
+	return ""
+}
+
+// setRSPExcludedClusters records the excluded clusters in the RSP annotations, or removes the record if none.
+func setRSPExcludedClusters(annotations map[string]string, excluded map[string]string) error {
+	if len(excluded) == 0 {
+		delete(annotations, v1beta1.RSPExcludedClustersAnnotation)
+		return nil
+	}
+	b, err := json.Marshal(excluded)
+	if err != nil {
+		return err
+	}
+	annotations[v1beta1.RSPExcludedClustersAnnotation] = string(b)
+	return nil
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func helperReadyKubeFedCluster(name string, labels map[string]string) *fedcorev1b1.KubeFedCluster {
+	return &fedcorev1b1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-federation-system", Name: name, Labels: labels},
+		Status: fedcorev1b1.KubeFedClusterStatus{Conditions: []fedcorev1b1.ClusterCondition{
+			{Type: common.ClusterReady, Status: corev1.ConditionTrue},
+		}},
+	}
+}
+
+func Test_listCandidateClusters(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = fedcorev1b1.AddToScheme(scheme)
+	notReady := helperReadyKubeFedCluster("c3", map[string]string{"region": "tokyo"})
+	notReady.Status.Conditions[0].Status = corev1.ConditionFalse
+	offline := helperReadyKubeFedCluster("c4", map[string]string{"region": "tokyo"})
+	offline.Status.Conditions = append(offline.Status.Conditions, fedcorev1b1.ClusterCondition{Type: common.ClusterOffline, Status: corev1.ConditionTrue})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		helperReadyKubeFedCluster("c1", map[string]string{"region": "tokyo"}),
+		helperReadyKubeFedCluster("c2", nil),
+		notReady,
+		offline,
+	).Build()
+
+	tests := []struct {
+		name         string
+		placement    *util.GenericPlacementFields
+		want         []string
+		wantExcluded map[string]string
+		wantErr      bool
+	}{
+		{"clusters", &util.GenericPlacementFields{
+			Clusters: []util.GenericClusterReference{{Name: "c2"}, {Name: "c1"}, {Name: "c2"}, {Name: "c3"}, {Name: "c4"}, {Name: "c9"}},
+		}, []string{"c2", "c1"}, map[string]string{"c3": "NotReady", "c4": "Offline", "c9": "NotRegistered"}, false},
+		{"cluster_selector", &util.GenericPlacementFields{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": "tokyo"}},
+		}, []string{"c1"}, map[string]string{"c3": "NotReady", "c4": "Offline"}, false},
+		{"all_healthy", &util.GenericPlacementFields{
+			Clusters: []util.GenericClusterReference{{Name: "c1"}, {Name: "c2"}},
+		}, []string{"c1", "c2"}, map[string]string{}, false},
+		{"invalid_selector", &util.GenericPlacementFields{
+			ClusterSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "region", Operator: "foo"}}},
+		}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotExcluded, err := listCandidateClusters(context.Background(), c, tt.placement, "kube-federation-system")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("listCandidateClusters() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("listCandidateClusters() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+			if diff := cmp.Diff(gotExcluded, tt.wantExcluded); diff != "" {
+				t.Errorf("listCandidateClusters() excluded = %v, want %v, diff %s", gotExcluded, tt.wantExcluded, diff)
+			}
+		})
+	}
+}
+
+func Test_setRSPExcludedClusters(t *testing.T) {
+	annotations := map[string]string{}
+	if err := setRSPExcludedClusters(annotations, map[string]string{"c3": "NotReady"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := annotations[v1beta1.RSPExcludedClustersAnnotation], `{"c3":"NotReady"}`; got != want {
+		t.Errorf("setRSPExcludedClusters() = %v, want %v", got, want)
+	}
+	if err := setRSPExcludedClusters(annotations, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := annotations[v1beta1.RSPExcludedClustersAnnotation]; ok {
+		t.Errorf("setRSPExcludedClusters() did not remove the annotation")
+	}
+}
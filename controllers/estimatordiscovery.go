@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// listWAOEstimators returns the WAO-Estimator settings of the KubeFedClusters in the namespace,
+// where the clusters not listed in estimators are discovered with d (Ref. discoverWAOEstimators).
+// It returns estimators as is if d is nil.
+func listWAOEstimators(
+	ctx context.Context, c client.Reader, kubefedNamespace string, estimators map[string]*v1beta1.WAOEstimatorSetting, d *v1beta1.WAOEstimatorDiscovery,
+) (map[string]*v1beta1.WAOEstimatorSetting, error) {
+	if d == nil {
+		return estimators, nil
+	}
+	cl := &fedcorev1b1.KubeFedClusterList{}
+	if err := c.List(ctx, cl, &client.ListOptions{Namespace: kubefedNamespace}); err != nil {
+		return nil, err
+	}
+	return discoverWAOEstimators(ctx, kubefedNamespace, cl.Items, estimators, d), nil
+}
+
+// discoverWAOEstimators returns estimators with the WAO-Estimators of the clusters not listed in estimators discovered with d.
+// Clusters whose WAO-Estimators are not found are not included, so they are treated as their WAO-Estimators failed.
+// It returns estimators as is if d is nil.
+func discoverWAOEstimators(
+	ctx context.Context, kubefedNamespace string, clusters []fedcorev1b1.KubeFedCluster,
+	estimators map[string]*v1beta1.WAOEstimatorSetting, d *v1beta1.WAOEstimatorDiscovery,
+) map[string]*v1beta1.WAOEstimatorSetting {
+	if d == nil {
+		return estimators
+	}
+	lg := log.FromContext(ctx)
+
+	out := make(map[string]*v1beta1.WAOEstimatorSetting, len(clusters))
+	for k, v := range estimators {
+		out[k] = v
+	}
+	for i := range clusters {
+		kfc := &clusters[i]
+		if _, ok := out[kfc.Name]; ok {
+			continue
+		}
+		conf, err := discoverWAOEstimator(kubefedNamespace, kfc, d)
+		if err != nil {
+			lg.Info("unable to discover WAO-Estimator", "cluster", kfc.Name, "mode", d.Mode, "err", err)
+			continue
+		}
+		out[kfc.Name] = conf
+	}
+	return out
+}
+
+// discoverWAOEstimator returns the WAO-Estimator settings of the KubeFedCluster, which are d.Template with the discovered endpoint.
+//
+// With mode "Service", the endpoint is the API server proxy of the Service, and the CA bundle and the token of the KubeFedCluster are used.
+// Ref. newMemberClusterConfig
+func discoverWAOEstimator(kubefedNamespace string, kfc *fedcorev1b1.KubeFedCluster, d *v1beta1.WAOEstimatorDiscovery) (*v1beta1.WAOEstimatorSetting, error) {
+	conf := &v1beta1.WAOEstimatorSetting{}
+	if d.Template != nil {
+		conf = d.Template.DeepCopy()
+	}
+
+	switch d.Mode {
+	case v1beta1.WAOEstimatorDiscoveryModeAnnotation:
+		endpoint := kfc.Annotations[d.Key]
+		if endpoint == "" {
+			endpoint = kfc.Labels[d.Key]
+		}
+		if endpoint == "" {
+			return nil, fmt.Errorf("no annotation or label %s", d.Key)
+		}
+		if !strings.Contains(endpoint, "://") {
+			endpoint = "http://" + endpoint
+		}
+		u, err := url.ParseRequestURI(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid URL: %w", d.Key, err)
+		}
+		secured := len(conf.CABundle) > 0 || conf.InsecureSkipVerify ||
+			conf.ClientCertSecret != nil || conf.BearerTokenSecret != nil || conf.BasicAuthSecret != nil
+		if secured && u.Scheme != "https" {
+			return nil, fmt.Errorf("endpoint %s must be https to use TLS or authentication settings", endpoint)
+		}
+		conf.Endpoint = endpoint
+
+	case v1beta1.WAOEstimatorDiscoveryModeService:
+		svc := d.Service
+		if svc == nil {
+			return nil, fmt.Errorf("no service specified")
+		}
+		if kfc.Spec.APIEndpoint == "" {
+			return nil, fmt.Errorf("the api endpoint of cluster %s is empty", kfc.Name)
+		}
+		// do not send the token of the KubeFedCluster in plaintext
+		if u, err := url.ParseRequestURI(kfc.Spec.APIEndpoint); err != nil || u.Scheme != "https" {
+			return nil, fmt.Errorf("the api endpoint %s of cluster %s must be an https URL", kfc.Spec.APIEndpoint, kfc.Name)
+		}
+		if kfc.Spec.SecretRef.Name == "" {
+			return nil, fmt.Errorf("cluster %s does not have a secret name", kfc.Name)
+		}
+		scheme := svc.Scheme
+		if scheme == "" {
+			scheme = "http"
+		}
+		conf.Endpoint = fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s:%s/proxy",
+			strings.TrimSuffix(kfc.Spec.APIEndpoint, "/"), url.PathEscape(svc.Namespace),
+			scheme, url.PathEscape(svc.Name), url.PathEscape(svc.Port))
+		conf.CABundle = kfc.Spec.CABundle
+		conf.InsecureSkipVerify = false
+		for _, v := range kfc.Spec.DisabledTLSValidations {
+			if v == fedcorev1b1.TLSAll {
+				conf.InsecureSkipVerify = true
+				conf.CABundle = nil
+			}
+		}
+		conf.ClientCertSecret = nil
+		conf.BasicAuthSecret = nil
+		// the KubeFedCluster secret has the token in "token" (Ref. fedctrlutil.TokenKey)
+		conf.BearerTokenSecret = &v1beta1.SecretReference{Namespace: kubefedNamespace, Name: kfc.Spec.SecretRef.Name}
+
+	default:
+		return nil, fmt.Errorf("invalid mode %s", d.Mode)
+	}
+	return conf, nil
+}
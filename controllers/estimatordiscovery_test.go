@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_discoverWAOEstimators(t *testing.T) {
+	ns := "kube-federation-system"
+	helperCluster := func(name string, annotations, labels map[string]string) fedcorev1b1.KubeFedCluster {
+		return fedcorev1b1.KubeFedCluster{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Annotations: annotations, Labels: labels},
+			Spec: fedcorev1b1.KubeFedClusterSpec{
+				APIEndpoint: "https://" + name + ":6443/",
+				CABundle:    []byte("ca"),
+				SecretRef:   fedcorev1b1.LocalSecretReference{Name: name + "-token"},
+			},
+		}
+	}
+	key := v1beta1.WAOEstimatorEndpointAnnotation
+	clusters := []fedcorev1b1.KubeFedCluster{
+		helperCluster("c1", map[string]string{key: "http://c1:5657"}, nil),
+		helperCluster("c2", nil, map[string]string{key: "c2.example.com"}),
+		helperCluster("c3", map[string]string{key: "http://c3-annotation:5657"}, map[string]string{key: "c3-label"}),
+		helperCluster("c4", nil, nil),
+		helperCluster("c5", map[string]string{key: "http://c5:5657"}, nil),
+	}
+	insecure := helperCluster("c6", nil, nil)
+	insecure.Spec.DisabledTLSValidations = []fedcorev1b1.TLSValidation{fedcorev1b1.TLSAll}
+	plaintext := helperCluster("c7", nil, nil)
+	plaintext.Spec.APIEndpoint = "http://c7:8080/"
+	clusters = append(clusters, insecure, plaintext)
+	template := &v1beta1.WAOEstimatorSetting{Namespace: "default", Name: "default", CacheTTL: &metav1.Duration{Duration: time.Minute}}
+	estimators := map[string]*v1beta1.WAOEstimatorSetting{
+		"c5": {Endpoint: "http://localhost:5657", Namespace: "default", Name: "default"},
+	}
+	withEndpoint := func(endpoint string) *v1beta1.WAOEstimatorSetting {
+		v := template.DeepCopy()
+		v.Endpoint = endpoint
+		return v
+	}
+
+	tests := []struct {
+		name string
+		d    *v1beta1.WAOEstimatorDiscovery
+		want map[string]*v1beta1.WAOEstimatorSetting
+	}{
+		{
+			name: "no_discovery",
+			d:    nil,
+			want: estimators,
+		},
+		{
+			name: "annotation",
+			d:    &v1beta1.WAOEstimatorDiscovery{Mode: v1beta1.WAOEstimatorDiscoveryModeAnnotation, Key: key, Template: template},
+			want: map[string]*v1beta1.WAOEstimatorSetting{
+				"c1": withEndpoint("http://c1:5657"),
+				"c2": withEndpoint("http://c2.example.com"),
+				"c3": withEndpoint("http://c3-annotation:5657"),
+				"c5": estimators["c5"],
+			},
+		},
+		{
+			name: "annotation_requires_https",
+			d: &v1beta1.WAOEstimatorDiscovery{Mode: v1beta1.WAOEstimatorDiscoveryModeAnnotation, Key: key, Template: &v1beta1.WAOEstimatorSetting{
+				BearerTokenSecret: &v1beta1.SecretReference{Namespace: "default", Name: "token"},
+			}},
+			want: map[string]*v1beta1.WAOEstimatorSetting{
+				"c5": estimators["c5"],
+			},
+		},
+		{
+			name: "service",
+			d: &v1beta1.WAOEstimatorDiscovery{
+				Mode:     v1beta1.WAOEstimatorDiscoveryModeService,
+				Service:  &v1beta1.WAOEstimatorServiceReference{Namespace: "wao-system", Name: "wao-estimator", Port: "5656", Scheme: "http"},
+				Template: template,
+			},
+			want: func() map[string]*v1beta1.WAOEstimatorSetting {
+				m := map[string]*v1beta1.WAOEstimatorSetting{"c5": estimators["c5"]}
+				for _, c := range []string{"c1", "c2", "c3", "c4", "c6"} {
+					v := withEndpoint("https://" + c + ":6443/api/v1/namespaces/wao-system/services/http:wao-estimator:5656/proxy")
+					v.CABundle = []byte("ca")
+					v.BearerTokenSecret = &v1beta1.SecretReference{Namespace: ns, Name: c + "-token"}
+					m[c] = v
+				}
+				m["c6"].CABundle = nil
+				m["c6"].InsecureSkipVerify = true
+				return m
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := discoverWAOEstimators(context.Background(), ns, clusters, estimators, tt.d)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("discoverWAOEstimators() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_discoverWAOEstimator_serviceProxy(t *testing.T) {
+	// a fake API server that proxies requests to the WAO-Estimator Service
+	prefix := "/api/v1/namespaces/wao-system/services/http:wao-estimator:5656/proxy"
+	var n int32
+	handler := helperWAOEstimatorHandler(&n, 0, nil)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" || !strings.HasPrefix(r.URL.Path, prefix) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	kfc := &fedcorev1b1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-federation-system", Name: "c1"},
+		Spec: fedcorev1b1.KubeFedClusterSpec{
+			APIEndpoint: srv.URL,
+			CABundle:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}),
+			SecretRef:   fedcorev1b1.LocalSecretReference{Name: "c1-token"},
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-federation-system", Name: "c1-token"},
+		Data:       map[string][]byte{"token": []byte("t0ken")},
+	}).Build()
+	d := &v1beta1.WAOEstimatorDiscovery{
+		Mode:     v1beta1.WAOEstimatorDiscoveryModeService,
+		Service:  &v1beta1.WAOEstimatorServiceReference{Namespace: "wao-system", Name: "wao-estimator", Port: "5656", Scheme: "http"},
+		Template: &v1beta1.WAOEstimatorSetting{Namespace: "default", Name: "default"},
+	}
+
+	conf, err := discoverWAOEstimator("kube-federation-system", kfc, d)
+	if err != nil {
+		t.Fatalf("discoverWAOEstimator() error = %v", err)
+	}
+	got, err := newWAOEstimatorPool().send(context.Background(), c, conf, 100, 2)
+	if err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+	if diff := cmp.Diff(got, []float64{100, 200}); diff != "" {
+		t.Errorf("send() = %v, diff %s", got, diff)
+	}
+}
@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Nedopro2022/wao-estimator/pkg/estimator"
+	"github.com/Nedopro2022/wao-estimator/pkg/estimator/api"
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// errWAOEstimatorCircuitOpen is returned without sending requests while the circuit of the WAO-Estimator is open.
+var errWAOEstimatorCircuitOpen = errors.New("circuit breaker is open")
+
+// defaultWAOEstimatorPool is shared by all controllers for the manager's lifetime.
+var defaultWAOEstimatorPool = newWAOEstimatorPool()
+
+// waoEstimatorPool shares WAO-Estimator clients, estimated power increases and rate limiters across reconciles.
+type waoEstimatorPool struct {
+	mu       sync.Mutex
+	clients  map[waoEstimatorKey]*waoEstimatorClient
+	limiters map[string]*rate.Limiter // keyed by endpoint
+	breakers map[waoEstimatorKey]*waoEstimatorBreaker
+	cache    map[waoEstimatorCacheKey]*waoEstimatorCacheEntry
+
+	now func() time.Time
+}
+
+// waoEstimatorKey identifies an Estimator resource served by a WAO-Estimator endpoint.
+type waoEstimatorKey struct {
+	endpoint  string
+	namespace string
+	name      string
+}
+
+// waoEstimatorClient is a client of an Estimator built with the TLS and authentication settings
+// identified by fingerprint, so that it is rebuilt when the settings or the referenced Secrets are changed.
+type waoEstimatorClient struct {
+	client      *estimator.Client
+	transport   *http.Transport
+	fingerprint string
+}
+
+// waoEstimatorCredentials are the TLS and authentication settings of an Estimator with the referenced Secrets resolved.
+type waoEstimatorCredentials struct {
+	tlsConfig     *tls.Config
+	authorization string // the value of the Authorization header, or empty
+	fingerprint   string
+}
+
+// waoEstimatorCacheKey identifies an estimation.
+// The Estimator is included so that estimations are not reused after the settings are changed.
+type waoEstimatorCacheKey struct {
+	cluster   string
+	estimator waoEstimatorKey
+	cpuMilli  int
+	replicas  int
+}
+
+// waoEstimatorCacheEntry is an estimation, or an ongoing request whose result is shared by the concurrent callers.
+type waoEstimatorCacheEntry struct {
+	done    chan struct{}
+	ready   bool
+	costs   []float64
+	err     error
+	expires time.Time
+}
+
+// waoEstimatorBreaker is the circuit breaker state of an Estimator.
+// The circuit is open if failures reaches the threshold.
+type waoEstimatorBreaker struct {
+	failures  int32
+	openUntil time.Time
+	trial     bool // a trial request is ongoing after openUntil
+}
+
+// waoEstimatorAPIError is an error returned by the WAO-Estimator API, which is not retried.
+type waoEstimatorAPIError struct {
+	err error
+}
+
+func (e *waoEstimatorAPIError) Error() string { return e.err.Error() }
+func (e *waoEstimatorAPIError) Unwrap() error { return e.err }
+
+func newWAOEstimatorPool() *waoEstimatorPool {
+	return &waoEstimatorPool{
+		clients:  map[waoEstimatorKey]*waoEstimatorClient{},
+		limiters: map[string]*rate.Limiter{},
+		breakers: map[waoEstimatorKey]*waoEstimatorBreaker{},
+		cache:    map[waoEstimatorCacheKey]*waoEstimatorCacheEntry{},
+		now:      time.Now,
+	}
+}
+
+func newWAOEstimatorKey(conf *v1beta1.WAOEstimatorSetting) waoEstimatorKey {
+	return waoEstimatorKey{endpoint: conf.Endpoint, namespace: conf.Namespace, name: conf.Name}
+}
+
+// estimatePowerIncreases returns the estimated power increases of the cluster,
+// where the result[n-1] is the power increase of allocating n workloads.
+//
+// Concurrent calls for the same estimation are coalesced into one request,
+// and the result is reused until conf.CacheTTL elapses (no cache if nil). Failures are not cached.
+// The request runs independently of the callers' contexts, so a canceled caller only stops waiting for it.
+// The returned slice must not be modified.
+func (p *waoEstimatorPool) estimatePowerIncreases(
+	ctx context.Context, c client.Reader, cluster string, conf *v1beta1.WAOEstimatorSetting, cpuMilli, replicas int,
+) ([]float64, error) {
+	if conf == nil {
+		return nil, fmt.Errorf("no WAO-Estimator settings for cluster %s", cluster)
+	}
+	key := waoEstimatorCacheKey{cluster: cluster, estimator: newWAOEstimatorKey(conf), cpuMilli: cpuMilli, replicas: replicas}
+
+	p.mu.Lock()
+	if e, ok := p.cache[key]; ok {
+		if e.ready && p.now().Before(e.expires) {
+			p.mu.Unlock()
+			return e.costs, nil
+		}
+		if !e.ready {
+			// coalesce into the ongoing request
+			p.mu.Unlock()
+			return e.wait(ctx)
+		}
+	}
+	p.pruneLocked()
+	e := &waoEstimatorCacheEntry{done: make(chan struct{})}
+	p.cache[key] = e
+	p.mu.Unlock()
+
+	// NOTE: the request is not canceled with ctx as it is shared by the coalesced calls,
+	// but each attempt is bounded by conf.Timeout (Ref. waoEstimatorPool.send)
+	go p.fill(log.IntoContext(context.Background(), log.FromContext(ctx)), c, key, e, conf, cpuMilli, replicas)
+
+	return e.wait(ctx)
+}
+
+// fill requests the estimation of the entry and caches it until conf.CacheTTL elapses.
+func (p *waoEstimatorPool) fill(
+	ctx context.Context, c client.Reader, key waoEstimatorCacheKey, e *waoEstimatorCacheEntry, conf *v1beta1.WAOEstimatorSetting, cpuMilli, replicas int,
+) {
+	costs, err := p.request(ctx, c, conf, cpuMilli, replicas)
+
+	p.mu.Lock()
+	var ttl time.Duration
+	if conf.CacheTTL != nil {
+		ttl = conf.CacheTTL.Duration
+	}
+	e.costs, e.err = costs, err
+	e.ready = true
+	e.expires = p.now().Add(ttl)
+	if (e.err != nil || ttl <= 0) && p.cache[key] == e {
+		delete(p.cache, key)
+	}
+	p.mu.Unlock()
+	close(e.done)
+}
+
+// wait returns the estimation of the entry once it is ready, or the error of ctx if ctx is done before that.
+func (e *waoEstimatorCacheEntry) wait(ctx context.Context) ([]float64, error) {
+	select {
+	case <-e.done:
+		return e.costs, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pruneLocked removes expired estimations. p.mu must be held.
+func (p *waoEstimatorPool) pruneLocked() {
+	now := p.now()
+	for k, e := range p.cache {
+		if e.ready && !now.Before(e.expires) {
+			delete(p.cache, k)
+		}
+	}
+}
+
+// request calls the WAO-Estimator with retries, unless the circuit of the WAO-Estimator is open.
+func (p *waoEstimatorPool) request(ctx context.Context, c client.Reader, conf *v1beta1.WAOEstimatorSetting, cpuMilli, replicas int) ([]float64, error) {
+	lg := log.FromContext(ctx)
+
+	if err := p.allow(conf); err != nil {
+		return nil, err
+	}
+
+	attempts := 1
+	backoff := v1beta1.DefaultWAOEstimatorRetryBackoff
+	if r := conf.Retry; r != nil {
+		attempts += int(r.MaxRetries)
+		if r.Backoff != nil {
+			backoff = r.Backoff.Duration
+		}
+	}
+
+	var costs []float64
+	var err error
+	for i := 1; ; i++ {
+		costs, err = p.send(ctx, c, conf, cpuMilli, replicas)
+		var apiErr *waoEstimatorAPIError
+		if err == nil || i >= attempts || errors.As(err, &apiErr) || ctx.Err() != nil {
+			break
+		}
+		lg.Info("retry EstimatePowerConsumption", "endpoint", conf.Endpoint, "attempt", i, "backoff", backoff, "err", err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+		backoff *= 2
+	}
+
+	p.record(conf, err, ctx.Err() != nil)
+	return costs, err
+}
+
+// send calls the WAO-Estimator once within the timeout and the rate limit of the endpoint.
+// c is used to read the Secrets referenced by conf, and may be nil if no Secrets are referenced.
+func (p *waoEstimatorPool) send(ctx context.Context, c client.Reader, conf *v1beta1.WAOEstimatorSetting, cpuMilli, replicas int) ([]float64, error) {
+	lg := log.FromContext(ctx)
+
+	timeout := v1beta1.DefaultWAOEstimatorTimeout
+	if conf.Timeout != nil && conf.Timeout.Duration > 0 {
+		timeout = conf.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ec, err := p.client(ctx, c, conf)
+	if err != nil {
+		return nil, err
+	}
+	if l := p.limiter(conf); l != nil {
+		if err := l.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	lg.Info("call EstimatePowerConsumption", "endpoint", conf.Endpoint, "namespace", conf.Namespace, "name", conf.Name,
+		"cpuMilli", cpuMilli, "replicas", replicas)
+	pc, apiErr, err := ec.EstimatePowerConsumption(ctx, cpuMilli, replicas)
+	if err != nil {
+		return nil, err
+	}
+	if apiErr != nil {
+		return nil, &waoEstimatorAPIError{err: fmt.Errorf("%v (%w)", apiErr.Message, estimator.GetErrorFromCode(*apiErr))}
+	}
+	if pc == nil || pc.WattIncreases == nil || len(*pc.WattIncreases) != replicas {
+		return nil, fmt.Errorf("unexpected response: want %d watt increases", replicas)
+	}
+	return *pc.WattIncreases, nil
+}
+
+// allow returns errWAOEstimatorCircuitOpen if the circuit of the WAO-Estimator is open.
+// After the open duration, it allows only one trial request at a time.
+func (p *waoEstimatorPool) allow(conf *v1beta1.WAOEstimatorSetting) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.breakers[newWAOEstimatorKey(conf)]
+	threshold, _ := circuitBreakerSettings(conf.CircuitBreaker)
+	if !ok || threshold == 0 || b.failures < threshold {
+		return nil
+	}
+	if p.now().Before(b.openUntil) || b.trial {
+		return errWAOEstimatorCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// record updates the circuit of the WAO-Estimator with the result of a request.
+// Failures caused by the caller (e.g. the reconcile is canceled) are not counted.
+func (p *waoEstimatorPool) record(conf *v1beta1.WAOEstimatorSetting, err error, canceled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := newWAOEstimatorKey(conf)
+	threshold, openDuration := circuitBreakerSettings(conf.CircuitBreaker)
+	b, ok := p.breakers[key]
+	if err != nil && canceled {
+		if ok {
+			b.trial = false
+		}
+		return
+	}
+	if err == nil || threshold == 0 {
+		delete(p.breakers, key)
+		return
+	}
+	if !ok {
+		b = &waoEstimatorBreaker{}
+		p.breakers[key] = b
+	}
+	b.failures++
+	b.trial = false
+	if b.failures >= threshold {
+		b.openUntil = p.now().Add(openDuration)
+	}
+}
+
+// circuitOpen returns true if the circuit of the WAO-Estimator is open, including while a trial request is ongoing.
+func (p *waoEstimatorPool) circuitOpen(conf *v1beta1.WAOEstimatorSetting) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b, ok := p.breakers[newWAOEstimatorKey(conf)]
+	threshold, _ := circuitBreakerSettings(conf.CircuitBreaker)
+	return ok && threshold > 0 && b.failures >= threshold
+}
+
+// circuitBreakerSettings returns the failure threshold and the open duration, or zeros if disabled.
+func circuitBreakerSettings(cb *v1beta1.WAOEstimatorCircuitBreaker) (int32, time.Duration) {
+	if cb == nil {
+		return 0, 0
+	}
+	threshold := int32(v1beta1.DefaultWAOEstimatorCircuitBreakerFailureThreshold)
+	if cb.FailureThreshold > 0 {
+		threshold = cb.FailureThreshold
+	}
+	openDuration := v1beta1.DefaultWAOEstimatorCircuitBreakerOpenDuration
+	if cb.OpenDuration != nil && cb.OpenDuration.Duration > 0 {
+		openDuration = cb.OpenDuration.Duration
+	}
+	return threshold, openDuration
+}
+
+// client returns the client of the Estimator, creating it on first use
+// and recreating it when the TLS and authentication settings are changed.
+func (p *waoEstimatorPool) client(ctx context.Context, c client.Reader, conf *v1beta1.WAOEstimatorSetting) (*estimator.Client, error) {
+	key := newWAOEstimatorKey(conf)
+
+	creds, err := loadWAOEstimatorCredentials(ctx, c, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old, ok := p.clients[key]
+	if ok && old.fingerprint == creds.fingerprint {
+		return old.client, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = creds.tlsConfig
+	opts := []estimator.ClientOption{api.WithHTTPClient(&http.Client{Transport: transport})}
+	if creds.authorization != "" {
+		opts = append(opts, estimator.ClientOptionAddRequestHeader("Authorization", creds.authorization))
+	}
+	ec, err := estimator.NewClient(conf.Endpoint, conf.Namespace, conf.Name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		old.transport.CloseIdleConnections()
+	}
+	p.clients[key] = &waoEstimatorClient{client: ec, transport: transport, fingerprint: creds.fingerprint}
+	return ec, nil
+}
+
+// loadWAOEstimatorCredentials resolves the TLS and authentication settings of the Estimator.
+// The Secrets are read on every call so that rotated credentials are used without restarting the manager.
+func loadWAOEstimatorCredentials(ctx context.Context, c client.Reader, conf *v1beta1.WAOEstimatorSetting) (*waoEstimatorCredentials, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "insecureSkipVerify=%t\n", conf.InsecureSkipVerify)
+	h.Write(conf.CABundle)
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: conf.InsecureSkipVerify,
+	}
+	if len(conf.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(conf.CABundle) {
+			return nil, fmt.Errorf("caBundle contains no valid PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if ref := conf.ClientCertSecret; ref != nil {
+		data, err := getSecretData(ctx, c, ref, corev1.TLSCertKey, corev1.TLSPrivateKeyKey)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		h.Write(data[corev1.TLSCertKey])
+		h.Write(data[corev1.TLSPrivateKeyKey])
+	}
+
+	var authorization string
+	switch {
+	case conf.BearerTokenSecret != nil:
+		data, err := getSecretData(ctx, c, conf.BearerTokenSecret, "token")
+		if err != nil {
+			return nil, err
+		}
+		authorization = "Bearer " + string(data["token"])
+	case conf.BasicAuthSecret != nil:
+		data, err := getSecretData(ctx, c, conf.BasicAuthSecret, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey)
+		if err != nil {
+			return nil, err
+		}
+		authorization = "Basic " + base64.StdEncoding.EncodeToString(
+			[]byte(string(data[corev1.BasicAuthUsernameKey])+":"+string(data[corev1.BasicAuthPasswordKey])))
+	}
+	fmt.Fprintf(h, "\nauthorization=%s", authorization)
+
+	return &waoEstimatorCredentials{
+		tlsConfig:     tlsConfig,
+		authorization: authorization,
+		fingerprint:   hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// getSecretData returns the data of the Secret, which must contain the given keys.
+func getSecretData(ctx context.Context, c client.Reader, ref *v1beta1.SecretReference, keys ...string) (map[string][]byte, error) {
+	if c == nil {
+		return nil, fmt.Errorf("unable to read secret %s/%s: no client", ref.Namespace, ref.Name)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, fmt.Errorf("unable to get secret %s/%s: %w", ref.Namespace, ref.Name, err)
+	}
+	for _, k := range keys {
+		if len(secret.Data[k]) == 0 {
+			return nil, fmt.Errorf("secret %s/%s has no %s", ref.Namespace, ref.Name, k)
+		}
+	}
+	return secret.Data, nil
+}
+
+// limiter returns the rate limiter of the endpoint updated with conf.RateLimit, or nil if no limit.
+func (p *waoEstimatorPool) limiter(conf *v1beta1.WAOEstimatorSetting) *rate.Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rl := conf.RateLimit
+	if rl == nil || rl.QPS <= 0 {
+		delete(p.limiters, conf.Endpoint)
+		return nil
+	}
+	limit := rate.Limit(rl.QPS)
+	burst := int(rl.Burst)
+	if burst <= 0 {
+		burst = int(rl.QPS)
+	}
+
+	l, ok := p.limiters[conf.Endpoint]
+	if !ok {
+		l = rate.NewLimiter(limit, burst)
+		p.limiters[conf.Endpoint] = l
+		return l
+	}
+	if l.Limit() != limit {
+		l.SetLimit(limit)
+	}
+	if l.Burst() != burst {
+		l.SetBurst(burst)
+	}
+	return l
+}
@@ -0,0 +1,518 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Nedopro2022/wao-estimator/pkg/estimator/api"
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// helperWAOEstimatorServer returns a fake WAO-Estimator that estimates cpuMilli watts per workload,
+// and the counter of the requests. Only the Estimator default/default exists.
+// fail is optional, and the n-th request responds 503 if fail(n) returns true.
+func helperWAOEstimatorServer(t *testing.T, delay time.Duration, fail func(n int32) bool) (*httptest.Server, *int32) {
+	var n int32
+	srv := httptest.NewServer(helperWAOEstimatorHandler(&n, delay, fail))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+// helperWAOEstimatorHandler is the handler of helperWAOEstimatorServer.
+func helperWAOEstimatorHandler(n *int32, delay time.Duration, fail func(n int32) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		i := atomic.AddInt32(n, 1)
+		if fail != nil && fail(i) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		var req api.PowerConsumption
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/namespaces/default/estimators/default/values/powerconsumption" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": "ErrServerEstimatorNotFound", "message": "estimator not found"}`))
+			return
+		}
+		time.Sleep(delay)
+		wis := make([]float64, req.NumWorkloads)
+		for i := range wis {
+			wis[i] = float64(req.CpuMilli * (i + 1))
+		}
+		req.WattIncreases = &wis
+		_ = json.NewEncoder(w).Encode(req)
+	}
+}
+
+func Test_waoEstimatorPool_estimatePowerIncreases(t *testing.T) {
+	srv, n := helperWAOEstimatorServer(t, 0, nil)
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := newWAOEstimatorPool()
+	p.now = func() time.Time { return now }
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default", CacheTTL: &metav1.Duration{Duration: time.Minute}}
+	noCache := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default", CacheTTL: &metav1.Duration{}}
+	wrongName := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "foo", CacheTTL: conf.CacheTTL}
+
+	tests := []struct {
+		name         string
+		advance      time.Duration
+		cluster      string
+		conf         *v1beta1.WAOEstimatorSetting
+		cpuMilli     int
+		replicas     int
+		want         []float64
+		wantErr      bool
+		wantRequests int32
+	}{
+		{"first", 0, "c1", conf, 100, 2, []float64{100, 200}, false, 1},
+		{"cached", 30 * time.Second, "c1", conf, 100, 2, []float64{100, 200}, false, 1},
+		{"other_cluster", 0, "c2", conf, 100, 2, []float64{100, 200}, false, 2},
+		{"other_cpu", 0, "c1", conf, 200, 2, []float64{200, 400}, false, 3},
+		{"other_replicas", 0, "c1", conf, 100, 3, []float64{100, 200, 300}, false, 4},
+		{"expired", 30 * time.Second, "c1", conf, 100, 2, []float64{100, 200}, false, 5},
+		{"no_cache", 0, "c3", noCache, 100, 1, []float64{100}, false, 6},
+		{"no_cache_again", 0, "c3", noCache, 100, 1, []float64{100}, false, 7},
+		{"error", 0, "c4", wrongName, 100, 1, nil, true, 8},
+		{"error_not_cached", 0, "c4", wrongName, 100, 1, nil, true, 9},
+		{"no_settings", 0, "c5", nil, 100, 1, nil, true, 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			got, err := p.estimatePowerIncreases(context.Background(), nil, tt.cluster, tt.conf, tt.cpuMilli, tt.replicas)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("estimatePowerIncreases() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("estimatePowerIncreases() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+			if got := atomic.LoadInt32(n); got != tt.wantRequests {
+				t.Errorf("estimatePowerIncreases() requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_waoEstimatorPool_coalesce(t *testing.T) {
+	srv, n := helperWAOEstimatorServer(t, 100*time.Millisecond, nil)
+	p := newWAOEstimatorPool()
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := p.estimatePowerIncreases(context.Background(), nil, "c1", conf, 100, 2)
+			if err != nil || len(got) != 2 {
+				t.Errorf("estimatePowerIncreases() = %v, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("estimatePowerIncreases() requests = %v, want 1", got)
+	}
+	if len(p.cache) != 0 {
+		t.Errorf("estimatePowerIncreases() cached %v without cacheTTL", p.cache)
+	}
+}
+
+func Test_waoEstimatorPool_coalesceCanceled(t *testing.T) {
+	srv, n := helperWAOEstimatorServer(t, 200*time.Millisecond, nil)
+	p := newWAOEstimatorPool()
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default"}
+
+	// the first caller starts the request and is canceled while the others are waiting for it
+	ctx, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		if _, err := p.estimatePowerIncreases(ctx, nil, "c1", conf, 100, 2); !errors.Is(err, context.Canceled) {
+			t.Errorf("estimatePowerIncreases() error = %v, want %v", err, context.Canceled)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := p.estimatePowerIncreases(context.Background(), nil, "c1", conf, 100, 2)
+			if err != nil || len(got) != 2 {
+				t.Errorf("estimatePowerIncreases() = %v, %v", got, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-firstDone:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("estimatePowerIncreases() did not return on cancellation")
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("estimatePowerIncreases() requests = %v, want 1", got)
+	}
+}
+
+func Test_waoEstimatorPool_limiter(t *testing.T) {
+	p := newWAOEstimatorPool()
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: "http://localhost:5657"}
+
+	if l := p.limiter(conf); l != nil {
+		t.Errorf("limiter() = %v, want nil", l)
+	}
+
+	conf.RateLimit = &v1beta1.WAOEstimatorRateLimit{QPS: 5}
+	l := p.limiter(conf)
+	if l == nil || l.Limit() != 5 || l.Burst() != 5 {
+		t.Fatalf("limiter() = %v, want limit 5 burst 5", l)
+	}
+
+	// shared by the endpoint and updated with the most recent settings
+	other := &v1beta1.WAOEstimatorSetting{Endpoint: conf.Endpoint, Name: "foo", RateLimit: &v1beta1.WAOEstimatorRateLimit{QPS: 10, Burst: 20}}
+	if l2 := p.limiter(other); l2 != l || l.Limit() != 10 || l.Burst() != 20 {
+		t.Errorf("limiter() = %v, want the same limiter with limit 10 burst 20", l2)
+	}
+
+	conf.RateLimit = nil
+	if l := p.limiter(conf); l != nil || len(p.limiters) != 0 {
+		t.Errorf("limiter() = %v, want nil and removed", l)
+	}
+}
+
+func Test_waoEstimatorPool_rateLimit(t *testing.T) {
+	srv, n := helperWAOEstimatorServer(t, 0, nil)
+	p := newWAOEstimatorPool()
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default",
+		RateLimit: &v1beta1.WAOEstimatorRateLimit{QPS: 1, Burst: 1}}
+
+	if _, err := p.estimatePowerIncreases(context.Background(), nil, "c1", conf, 100, 1); err != nil {
+		t.Fatalf("estimatePowerIncreases() error = %v", err)
+	}
+	// the next request must wait for about a second, so it exceeds the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.estimatePowerIncreases(ctx, nil, "c2", conf, 100, 1); err == nil {
+		t.Errorf("estimatePowerIncreases() error = nil, want rate limited")
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("estimatePowerIncreases() requests = %v, want 1", got)
+	}
+}
+
+func Test_waoEstimatorPool_retry(t *testing.T) {
+	// the first 2 requests fail
+	srv, n := helperWAOEstimatorServer(t, 0, func(n int32) bool { return n <= 2 })
+	backoff := &metav1.Duration{Duration: time.Millisecond}
+
+	tests := []struct {
+		name         string
+		conf         *v1beta1.WAOEstimatorSetting
+		sent         int32 // the number of requests already sent
+		wantErr      bool
+		wantRequests int32
+	}{
+		{"no_retry", &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default"}, 0, true, 1},
+		{"not_enough", &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default",
+			Retry: &v1beta1.WAOEstimatorRetry{MaxRetries: 1, Backoff: backoff}}, 0, true, 2},
+		{"succeeded", &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default",
+			Retry: &v1beta1.WAOEstimatorRetry{MaxRetries: 2, Backoff: backoff}}, 0, false, 3},
+		{"api_error", &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "foo",
+			Retry: &v1beta1.WAOEstimatorRetry{MaxRetries: 2, Backoff: backoff}}, 2, true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(n, tt.sent)
+			p := newWAOEstimatorPool()
+			_, err := p.request(context.Background(), nil, tt.conf, 100, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("request() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(n); got != tt.wantRequests {
+				t.Errorf("request() requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_waoEstimatorPool_timeout(t *testing.T) {
+	srv, _ := helperWAOEstimatorServer(t, 500*time.Millisecond, nil)
+	p := newWAOEstimatorPool()
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default", Timeout: &metav1.Duration{Duration: 50 * time.Millisecond}}
+
+	start := time.Now()
+	if _, err := p.request(context.Background(), nil, conf, 100, 1); err == nil {
+		t.Errorf("request() error = nil, want timeout")
+	}
+	if d := time.Since(start); d >= 500*time.Millisecond {
+		t.Errorf("request() took %v, want less than 500ms", d)
+	}
+}
+
+func Test_waoEstimatorPool_circuitBreaker(t *testing.T) {
+	var failing int32 = 1
+	srv, n := helperWAOEstimatorServer(t, 0, func(int32) bool { return atomic.LoadInt32(&failing) == 1 })
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := newWAOEstimatorPool()
+	p.now = func() time.Time { return now }
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default",
+		CircuitBreaker: &v1beta1.WAOEstimatorCircuitBreaker{FailureThreshold: 2, OpenDuration: &metav1.Duration{Duration: time.Minute}}}
+
+	tests := []struct {
+		name         string
+		advance      time.Duration
+		failing      int32
+		wantErr      error // nil means any error if wantOK is false
+		wantOK       bool
+		wantRequests int32
+		wantOpen     bool
+	}{
+		{"failure_1", 0, 1, nil, false, 1, false},
+		{"failure_2_opens", 0, 1, nil, false, 2, true},
+		{"open", 30 * time.Second, 0, errWAOEstimatorCircuitOpen, false, 2, true},
+		{"trial_fails", 30 * time.Second, 1, nil, false, 3, true},
+		{"reopened", 30 * time.Second, 0, errWAOEstimatorCircuitOpen, false, 3, true},
+		{"trial_succeeds", 30 * time.Second, 0, nil, true, 4, false},
+		{"closed", 0, 1, nil, false, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			atomic.StoreInt32(&failing, tt.failing)
+			_, err := p.request(context.Background(), nil, conf, 100, 1)
+			if (err == nil) != tt.wantOK || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Errorf("request() error = %v, wantOK %v, wantErr %v", err, tt.wantOK, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(n); got != tt.wantRequests {
+				t.Errorf("request() requests = %v, want %v", got, tt.wantRequests)
+			}
+			if got := p.circuitOpen(conf); got != tt.wantOpen {
+				t.Errorf("circuitOpen() = %v, want %v", got, tt.wantOpen)
+			}
+		})
+	}
+
+	// canceled requests are not counted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		_, _ = p.request(ctx, nil, conf, 100, 1)
+	}
+	if p.circuitOpen(conf) {
+		t.Errorf("circuitOpen() = true after canceled requests, want false")
+	}
+}
+
+func Test_estimatePowerIncreasesWAO(t *testing.T) {
+	srv, _ := helperWAOEstimatorServer(t, 0, nil)
+	inf := math.Inf(1)
+	estimators := map[string]*v1beta1.WAOEstimatorSetting{
+		"c1": {Endpoint: srv.URL, Namespace: "default", Name: "default"},
+		"c3": {Endpoint: "://invalid", Namespace: "default", Name: "default"},
+		"c4": {Endpoint: srv.URL, Namespace: "default", Name: "foo"},
+	}
+	want := [][]float64{{100, 200}, {inf, inf}, {inf, inf}, {inf, inf}}
+	got := estimatePowerIncreasesWAO(context.Background(), nil, []string{"c1", "c2", "c3", "c4"}, estimators, 100, 2)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("estimatePowerIncreasesWAO() = %v, want %v, diff %s", got, want, diff)
+	}
+}
+
+// helperSelfSignedCert returns a self-signed client certificate and its private key, PEM encoded.
+func helperSelfSignedCert(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "waofed"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func Test_waoEstimatorPool_tls(t *testing.T) {
+	certPEM, keyPEM := helperSelfSignedCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(certPEM)
+
+	var n int32
+	handler := helperWAOEstimatorHandler(&n, 0, nil)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" && r.Header.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "token"},
+			Data:       map[string][]byte{"token": []byte("t0ken")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "basic"},
+			Type:       corev1.SecretTypeBasicAuth,
+			Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "wrong"},
+			Data:       map[string][]byte{"token": []byte("wrong")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cert"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{"tls.crt": certPEM, "tls.key": keyPEM},
+		},
+	).Build()
+	token := &v1beta1.SecretReference{Namespace: "default", Name: "token"}
+
+	tests := []struct {
+		name    string
+		c       client.Reader
+		conf    *v1beta1.WAOEstimatorSetting
+		wantErr bool
+	}{
+		{
+			name: "bearer",
+			c:    c,
+			conf: &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BearerTokenSecret: token},
+		},
+		{
+			name: "basic",
+			c:    c,
+			conf: &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BasicAuthSecret: &v1beta1.SecretReference{Namespace: "default", Name: "basic"}},
+		},
+		{
+			name: "mtls",
+			c:    c,
+			conf: &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BearerTokenSecret: token, ClientCertSecret: &v1beta1.SecretReference{Namespace: "default", Name: "cert"}},
+		},
+		{
+			name: "insecure_skip_verify",
+			c:    c,
+			conf: &v1beta1.WAOEstimatorSetting{InsecureSkipVerify: true, BearerTokenSecret: token},
+		},
+		{
+			name:    "unknown_ca",
+			c:       c,
+			conf:    &v1beta1.WAOEstimatorSetting{BearerTokenSecret: token},
+			wantErr: true,
+		},
+		{
+			name:    "unauthorized",
+			c:       c,
+			conf:    &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BearerTokenSecret: &v1beta1.SecretReference{Namespace: "default", Name: "wrong"}},
+			wantErr: true,
+		},
+		{
+			name:    "secret_not_found",
+			c:       c,
+			conf:    &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BearerTokenSecret: &v1beta1.SecretReference{Namespace: "default", Name: "foo"}},
+			wantErr: true,
+		},
+		{
+			name:    "secret_missing_key",
+			c:       c,
+			conf:    &v1beta1.WAOEstimatorSetting{CABundle: caBundle, ClientCertSecret: token},
+			wantErr: true,
+		},
+		{
+			name:    "no_client",
+			c:       nil,
+			conf:    &v1beta1.WAOEstimatorSetting{CABundle: caBundle, BearerTokenSecret: token},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Endpoint, tt.conf.Namespace, tt.conf.Name = srv.URL, "default", "default"
+			p := newWAOEstimatorPool()
+			_, err := p.send(context.Background(), tt.c, tt.conf, 100, 1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_waoEstimatorPool_rotateSecret(t *testing.T) {
+	var n int32
+	handler := helperWAOEstimatorHandler(&n, 0, nil)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "token"},
+		Data:       map[string][]byte{"token": []byte("old")},
+	}
+	c := fake.NewClientBuilder().WithObjects(secret).Build()
+	conf := &v1beta1.WAOEstimatorSetting{
+		Endpoint: srv.URL, Namespace: "default", Name: "default", InsecureSkipVerify: true,
+		BearerTokenSecret: &v1beta1.SecretReference{Namespace: "default", Name: "token"},
+	}
+
+	p := newWAOEstimatorPool()
+	if _, err := p.send(context.Background(), c, conf, 100, 1); err == nil {
+		t.Fatalf("send() error = nil, want unauthorized")
+	}
+	secret.Data["token"] = []byte("new")
+	if err := c.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.send(context.Background(), c, conf, 100, 1); err != nil {
+		t.Errorf("send() error = %v after the secret is rotated", err)
+	}
+	if got := len(p.clients); got != 1 {
+		t.Errorf("len(clients) = %v, want 1", got)
+	}
+}
@@ -24,12 +24,35 @@ var federatedDeploymentGVK = schema.GroupVersionKind{
 	Version: "v1beta1",
 }
 
+var federatedReplicaSetGVK = schema.GroupVersionKind{
+	Group:   "types.kubefed.io",
+	Kind:    "FederatedReplicaSet",
+	Version: "v1beta1",
+}
+
+// replicaSchedulingGVKs lists replica-bearing federated types that can be handled as structuredFederatedDeployment.
+//
+// NOTE: KubeFed RSP only supports FederatedDeployment and FederatedReplicaSet as spec.targetKind,
+// so other kinds (e.g. FederatedStatefulSet) are not listed here.
+var replicaSchedulingGVKs = []schema.GroupVersionKind{
+	federatedDeploymentGVK,
+	federatedReplicaSetGVK,
+}
+
 func newUnstructuredFederatedDeployment() *unstructured.Unstructured {
+	return newUnstructuredFederatedObject(federatedDeploymentGVK)
+}
+
+func newUnstructuredFederatedObject(gvk schema.GroupVersionKind) *unstructured.Unstructured {
 	u := &unstructured.Unstructured{}
-	u.SetGroupVersionKind(federatedDeploymentGVK)
+	u.SetGroupVersionKind(gvk)
 	return u
 }
 
+// structuredFederatedDeployment represents a FederatedDeployment or other replica-bearing federated types listed in replicaSchedulingGVKs.
+//
+// NOTE: Templates of other kinds (e.g. ReplicaSet) are decoded into appsv1.Deployment,
+// as they share the fields WAOFed uses (spec.replicas, spec.selector and spec.template).
 type structuredFederatedDeployment struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -39,18 +62,20 @@ type structuredFederatedDeployment struct {
 type structuredFederatedDeploymentSpec struct {
 	Template  *appsv1.Deployment                  `json:"template,omitempty"`
 	Placement *fedctrlutil.GenericPlacementFields `json:"placement,omitempty"`
+	Overrides []fedctrlutil.GenericOverrideItem   `json:"overrides,omitempty"`
 }
 
 func convertToStructuredFederatedDeployment(in *unstructured.Unstructured) (*structuredFederatedDeployment, error) {
 	var out structuredFederatedDeployment
 	out.Spec = &structuredFederatedDeploymentSpec{}
 
-	if in.GroupVersionKind() != federatedDeploymentGVK {
-		return nil, fmt.Errorf("wrong GVK: %v", in.GroupVersionKind())
+	gvk := in.GroupVersionKind()
+	if !isReplicaSchedulingGVK(gvk) {
+		return nil, fmt.Errorf("wrong GVK: %v", gvk)
 	}
 	out.TypeMeta = metav1.TypeMeta{
-		Kind:       federatedDeploymentGVK.Kind,
-		APIVersion: federatedDeploymentGVK.GroupVersion().Identifier(),
+		Kind:       gvk.Kind,
+		APIVersion: gvk.GroupVersion().Identifier(),
 	}
 
 	objMeta, err := convertUnstructuredFieldToObject[*metav1.ObjectMeta]("metadata", in.Object)
@@ -78,9 +103,56 @@ func convertToStructuredFederatedDeployment(in *unstructured.Unstructured) (*str
 		out.Spec.Template = objDeployment
 	}
 
+	objOverrides, err := convertUnstructuredFieldToObject[[]fedctrlutil.GenericOverrideItem]("overrides", spec)
+	if err == nil {
+		out.Spec.Overrides = objOverrides
+	}
+
 	return &out, nil
 }
 
+// templateReplicas returns spec.template.spec.replicas, or 1 if not specified as the API server defaults it to 1.
+// The caller must ensure spec.template != nil.
+func (r *structuredFederatedDeployment) templateReplicas() int32 {
+	if r.Spec.Template.Spec.Replicas == nil {
+		return 1
+	}
+	return *r.Spec.Template.Spec.Replicas
+}
+
+// currentReplicas returns the number of replicas of each cluster in spec.overrides,
+// which KubeFed writes to distribute replicas according to the RSP.
+func (r *structuredFederatedDeployment) currentReplicas() map[string]int64 {
+	out := map[string]int64{}
+	if r.Spec == nil {
+		return out
+	}
+	for _, o := range r.Spec.Overrides {
+		for _, co := range o.ClusterOverrides {
+			if co.Path != "/spec/replicas" || co.Op == "remove" {
+				continue
+			}
+			// NOTE: numbers are decoded as float64 via JSON
+			switch v := co.Value.(type) {
+			case float64:
+				out[o.ClusterName] = int64(v)
+			case int64:
+				out[o.ClusterName] = v
+			}
+		}
+	}
+	return out
+}
+
+func isReplicaSchedulingGVK(gvk schema.GroupVersionKind) bool {
+	for _, v := range replicaSchedulingGVKs {
+		if gvk == v {
+			return true
+		}
+	}
+	return false
+}
+
 func convertUnstructuredFieldToObject[T any](fieldName string, unstructuredObj map[string]any) (T, error) {
 	var obj T
 	v, ok := unstructuredObj[fieldName]
@@ -128,6 +128,67 @@ func Test_convertToStructuredFederatedDeployment(t *testing.T) {
 			},
 			false,
 		},
+		{"replicaset",
+			args{&unstructured.Unstructured{Object: helperLoadJSON(t, "testdata/unstructuredFederatedReplicaSetObject.json")}},
+			&structuredFederatedDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       federatedReplicaSetGVK.Kind,
+					APIVersion: federatedReplicaSetGVK.GroupVersion().Identifier(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "frs-sample",
+					Namespace: "default",
+				},
+				Spec: &structuredFederatedDeploymentSpec{
+					Placement: &util.GenericPlacementFields{
+						Clusters: []util.GenericClusterReference{
+							{Name: "kind-waofed-1"}, {Name: "kind-waofed-2"}},
+					},
+					Template: &appsv1.Deployment{
+						Spec: appsv1.DeploymentSpec{
+							Replicas: pointer.Int32(4),
+							Template: corev1.PodTemplateSpec{
+								Spec: corev1.PodSpec{
+									Containers: []corev1.Container{
+										{
+											Name:  "nginx",
+											Image: "nginx:1.23.2",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+		{"overrides",
+			args{&unstructured.Unstructured{Object: helperLoadJSON(t, "testdata/unstructuredFederatedDeploymentObject_overrides.json")}},
+			&structuredFederatedDeployment{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       federatedDeploymentGVK.Kind,
+					APIVersion: federatedDeploymentGVK.GroupVersion().Identifier(),
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "fdeploy-sample",
+					Namespace: "default",
+				},
+				Spec: &structuredFederatedDeploymentSpec{
+					Placement: &util.GenericPlacementFields{
+						Clusters: []util.GenericClusterReference{
+							{Name: "kind-waofed-1"}, {Name: "kind-waofed-2"}, {Name: "kind-waofed-3"}},
+					},
+					Overrides: []util.GenericOverrideItem{
+						{ClusterName: "kind-waofed-1", ClusterOverrides: []util.ClusterOverride{{Path: "/spec/replicas", Value: float64(5)}}},
+						{ClusterName: "kind-waofed-2", ClusterOverrides: []util.ClusterOverride{
+							{Path: "/spec/replicas", Value: float64(4)}, {Path: "/metadata/labels", Value: map[string]any{"foo": "bar"}}}},
+						{ClusterName: "kind-waofed-3", ClusterOverrides: []util.ClusterOverride{{Path: "/spec/replicas", Value: float64(0)}}},
+					},
+				},
+			},
+			false,
+		},
 		{"wrong GVK",
 			args{&unstructured.Unstructured{Object: helperLoadJSON(t, "testdata/unstructuredFederatedDeploymentObject_wrong_GVK.json")}},
 			&structuredFederatedDeployment{},
@@ -171,6 +232,8 @@ func Test_convertToStructuredFederatedDeployment(t *testing.T) {
 			compare("Namespace", got.Namespace, tt.want.Namespace)
 			// placement
 			compare("Clusters", got.Spec.Placement, tt.want.Spec.Placement)
+			// overrides
+			compare("Overrides", got.Spec.Overrides, tt.want.Spec.Overrides)
 			// template
 			if tt.want.Spec.Template != nil {
 				compare("Replicas", got.Spec.Template.Spec.Replicas, tt.want.Spec.Template.Spec.Replicas)
@@ -183,6 +246,34 @@ func Test_convertToStructuredFederatedDeployment(t *testing.T) {
 	}
 }
 
+func Test_structuredFederatedDeploymentCurrentReplicas(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []util.GenericOverrideItem
+		want      map[string]int64
+	}{
+		{"no_overrides", nil, map[string]int64{}},
+		{"replicas", []util.GenericOverrideItem{
+			{ClusterName: "c1", ClusterOverrides: []util.ClusterOverride{{Path: "/spec/replicas", Value: float64(5)}}},
+			{ClusterName: "c2", ClusterOverrides: []util.ClusterOverride{{Path: "/metadata/labels", Value: map[string]any{}}, {Op: "replace", Path: "/spec/replicas", Value: int64(4)}}},
+			{ClusterName: "c3", ClusterOverrides: []util.ClusterOverride{{Path: "/spec/replicas", Value: float64(0)}}},
+		}, map[string]int64{"c1": 5, "c2": 4, "c3": 0}},
+		{"ignored", []util.GenericOverrideItem{
+			{ClusterName: "c1", ClusterOverrides: []util.ClusterOverride{{Op: "remove", Path: "/spec/replicas"}}},
+			{ClusterName: "c2", ClusterOverrides: []util.ClusterOverride{{Path: "/spec/replicas", Value: "4"}}},
+		}, map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fdeploy := &structuredFederatedDeployment{Spec: &structuredFederatedDeploymentSpec{Overrides: tt.overrides}}
+			got := fdeploy.currentReplicas()
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("currentReplicas() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
 func helperOpen(t *testing.T, name string) *os.File {
 	f, err := os.Open(name)
 	if err != nil {
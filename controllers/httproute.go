@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// NOTE: Gateway API is handled as unstructured objects so that WAOFed does not depend on a specific Gateway API release.
+var httpRouteGVK = schema.GroupVersionKind{
+	Group:   "gateway.networking.k8s.io",
+	Kind:    "HTTPRoute",
+	Version: "v1beta1",
+}
+
+func newUnstructuredHTTPRoute() *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(httpRouteGVK)
+	return u
+}
+
+// httpRouteMaxWeight is the maximum backendRefs[].weight allowed by Gateway API.
+const httpRouteMaxWeight = 1000000
+
+// httpRouteBackendKinds lists backendRefs[].group/kind that may represent per-cluster backends.
+var httpRouteBackendKinds = map[schema.GroupKind]struct{}{
+	{Group: "", Kind: "Service"}:                            {},
+	{Group: "multicluster.x-k8s.io", Kind: "ServiceImport"}: {},
+}
+
+// normalizeSLPWeights resolves the weight of each cluster from SLP spec.clusters
+// and normalizes them into the range of Gateway API weights.
+//
+//   - An explicit mapping takes precedence over "*", and clusters without preferences get weight 0 (no access).
+//   - Negative weights are regarded as 0.
+//   - If all weights are 0, all clusters get the same weight so that the route keeps serving,
+//     which is reported by the second return value.
+//   - Weights are scaled down proportionally if the maximum exceeds httpRouteMaxWeight,
+//     while positive weights are kept positive.
+func normalizeSLPWeights(prefs map[string]v1beta1.ClusterPreferences, clusters []string) (map[string]int64, bool) {
+	weights := make(map[string]int64, len(clusters))
+	var max int64
+	for _, c := range clusters {
+		pref, ok := prefs[c]
+		if !ok {
+			pref, ok = prefs["*"]
+		}
+		var w int64
+		if ok && pref.Weight > 0 {
+			w = pref.Weight
+		}
+		weights[c] = w
+		if w > max {
+			max = w
+		}
+	}
+
+	if max == 0 && len(clusters) > 0 {
+		for c := range weights {
+			weights[c] = 1
+		}
+		return weights, true
+	}
+	if max <= httpRouteMaxWeight {
+		return weights, false
+	}
+	for c, w := range weights {
+		if w == 0 {
+			continue
+		}
+		scaled := int64(math.Floor(float64(w) / float64(max) * httpRouteMaxWeight))
+		if scaled < 1 {
+			scaled = 1
+		}
+		weights[c] = scaled
+	}
+	return weights, false
+}
+
+// setHTTPRouteBackendWeights sets spec.rules[].backendRefs[].weight of the HTTPRoute according to SLP spec.clusters,
+// and reports whether anything has been changed and whether any rule has fallen back to equal weights (Ref. normalizeSLPWeights).
+//
+// Each backendRef named "<backendPrefix><cluster>" (a Service or a ServiceImport) is regarded as the backend in the cluster,
+// and the others are left as is.
+func setHTTPRouteBackendWeights(route *unstructured.Unstructured, backendPrefix string, prefs map[string]v1beta1.ClusterPreferences) (changed, equal bool, err error) {
+	rules, found, err := unstructured.NestedSlice(route.Object, "spec", "rules")
+	if err != nil {
+		return false, false, err
+	}
+	if !found {
+		return false, false, nil
+	}
+
+	for i := range rules {
+		rule, ok := rules[i].(map[string]any)
+		if !ok {
+			return false, false, fmt.Errorf("could not encode spec.rules[%d]", i)
+		}
+		refs, found, err := unstructured.NestedSlice(rule, "backendRefs")
+		if err != nil {
+			return false, false, err
+		}
+		if !found {
+			continue
+		}
+
+		// collect per-cluster backends
+		var clusters []string
+		backends := map[int]string{} // index in backendRefs -> cluster
+		for j := range refs {
+			ref, ok := refs[j].(map[string]any)
+			if !ok {
+				return false, false, fmt.Errorf("could not encode spec.rules[%d].backendRefs[%d]", i, j)
+			}
+			name, _, _ := unstructured.NestedString(ref, "name")
+			group, _, _ := unstructured.NestedString(ref, "group")
+			kind, _, _ := unstructured.NestedString(ref, "kind")
+			if kind == "" {
+				kind = "Service"
+			}
+			if _, ok := httpRouteBackendKinds[schema.GroupKind{Group: group, Kind: kind}]; !ok {
+				continue
+			}
+			cluster := strings.TrimPrefix(name, backendPrefix)
+			if cluster == name || cluster == "" {
+				continue
+			}
+			backends[j] = cluster
+			clusters = append(clusters, cluster)
+		}
+		if len(clusters) == 0 {
+			continue
+		}
+
+		// set weights
+		weights, fallback := normalizeSLPWeights(prefs, clusters)
+		equal = equal || fallback
+		for j, cluster := range backends {
+			ref := refs[j].(map[string]any)
+			cur, found, err := unstructured.NestedInt64(ref, "weight")
+			if err != nil {
+				return false, false, err
+			}
+			// NOTE: always set the weight explicitly as the default weight (1) would give access to the cluster
+			if found && cur == weights[cluster] {
+				continue
+			}
+			ref["weight"] = weights[cluster]
+			changed = true
+		}
+		rule["backendRefs"] = refs
+		rules[i] = rule
+	}
+
+	if !changed {
+		return false, equal, nil
+	}
+	if err := unstructured.SetNestedSlice(route.Object, rules, "spec", "rules"); err != nil {
+		return false, false, err
+	}
+	return true, equal, nil
+}
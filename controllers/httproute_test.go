@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_normalizeSLPWeights(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefs    map[string]v1beta1.ClusterPreferences
+		clusters []string
+		want     map[string]int64
+		wantEq   bool
+	}{
+		{"empty", nil, []string{}, map[string]int64{}, false},
+		{"explicit", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 1}, "c2": {Weight: 3},
+		}, []string{"c1", "c2"}, map[string]int64{"c1": 1, "c2": 3}, false},
+		{"no_preferences", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 1},
+		}, []string{"c1", "c2"}, map[string]int64{"c1": 1, "c2": 0}, false},
+		{"wildcard", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 5}, "*": {Weight: 2},
+		}, []string{"c1", "c2", "c3"}, map[string]int64{"c1": 5, "c2": 2, "c3": 2}, false},
+		{"negative", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: -1}, "c2": {Weight: 1},
+		}, []string{"c1", "c2"}, map[string]int64{"c1": 0, "c2": 1}, false},
+		{"all_zero", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 0}, "c2": {Weight: -1},
+		}, []string{"c1", "c2", "c3"}, map[string]int64{"c1": 1, "c2": 1, "c3": 1}, true},
+		{"max", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: httpRouteMaxWeight}, "c2": {Weight: 1},
+		}, []string{"c1", "c2"}, map[string]int64{"c1": httpRouteMaxWeight, "c2": 1}, false},
+		{"scale_down", map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 4 * httpRouteMaxWeight}, "c2": {Weight: 2 * httpRouteMaxWeight}, "c3": {Weight: 1}, "c4": {Weight: 0},
+		}, []string{"c1", "c2", "c3", "c4"}, map[string]int64{"c1": httpRouteMaxWeight, "c2": httpRouteMaxWeight / 2, "c3": 1, "c4": 0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotEq := normalizeSLPWeights(tt.prefs, tt.clusters)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("normalizeSLPWeights() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+			if gotEq != tt.wantEq {
+				t.Errorf("normalizeSLPWeights() equal = %v, want %v", gotEq, tt.wantEq)
+			}
+		})
+	}
+}
+
+func helperHTTPRoute(backendRefs ...map[string]any) *unstructured.Unstructured {
+	refs := make([]any, len(backendRefs))
+	for i := range backendRefs {
+		refs[i] = backendRefs[i]
+	}
+	u := newUnstructuredHTTPRoute()
+	u.Object["spec"] = map[string]any{
+		"rules": []any{
+			map[string]any{"backendRefs": refs},
+		},
+	}
+	return u
+}
+
+func Test_setHTTPRouteBackendWeights(t *testing.T) {
+	prefs := map[string]v1beta1.ClusterPreferences{
+		"c1": {Weight: 1},
+		"c2": {Weight: 3},
+	}
+	tests := []struct {
+		name        string
+		route       *unstructured.Unstructured
+		want        *unstructured.Unstructured
+		wantChanged bool
+		wantEqual   bool
+	}{
+		{"no_rules", newUnstructuredHTTPRoute(), newUnstructuredHTTPRoute(), false, false},
+		{"services", helperHTTPRoute(
+			map[string]any{"name": "svc-c1"},
+			map[string]any{"name": "svc-c2", "kind": "Service", "weight": int64(1)},
+			map[string]any{"name": "svc-c3"},
+		), helperHTTPRoute(
+			map[string]any{"name": "svc-c1", "weight": int64(1)},
+			map[string]any{"name": "svc-c2", "kind": "Service", "weight": int64(3)},
+			map[string]any{"name": "svc-c3", "weight": int64(0)},
+		), true, false},
+		{"serviceimports", helperHTTPRoute(
+			map[string]any{"name": "svc-c1", "group": "multicluster.x-k8s.io", "kind": "ServiceImport"},
+		), helperHTTPRoute(
+			map[string]any{"name": "svc-c1", "group": "multicluster.x-k8s.io", "kind": "ServiceImport", "weight": int64(1)},
+		), true, false},
+		{"unchanged", helperHTTPRoute(
+			map[string]any{"name": "svc-c1", "weight": int64(1)},
+			map[string]any{"name": "svc-c2", "weight": int64(3)},
+		), helperHTTPRoute(
+			map[string]any{"name": "svc-c1", "weight": int64(1)},
+			map[string]any{"name": "svc-c2", "weight": int64(3)},
+		), false, false},
+		{"other_backends", helperHTTPRoute(
+			map[string]any{"name": "other-c1", "weight": int64(5)},
+			map[string]any{"name": "svc-", "weight": int64(5)},
+			map[string]any{"name": "svc-c1", "kind": "Other", "weight": int64(5)},
+		), helperHTTPRoute(
+			map[string]any{"name": "other-c1", "weight": int64(5)},
+			map[string]any{"name": "svc-", "weight": int64(5)},
+			map[string]any{"name": "svc-c1", "kind": "Other", "weight": int64(5)},
+		), false, false},
+		{"all_zero", helperHTTPRoute(
+			map[string]any{"name": "svc-c3", "weight": int64(0)},
+			map[string]any{"name": "svc-c4"},
+		), helperHTTPRoute(
+			map[string]any{"name": "svc-c3", "weight": int64(1)},
+			map[string]any{"name": "svc-c4", "weight": int64(1)},
+		), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotChanged, gotEqual, err := setHTTPRouteBackendWeights(tt.route, "svc-", prefs)
+			if err != nil {
+				t.Errorf("setHTTPRouteBackendWeights() error = %v", err)
+				return
+			}
+			if gotChanged != tt.wantChanged {
+				t.Errorf("setHTTPRouteBackendWeights() = %v, want %v", gotChanged, tt.wantChanged)
+			}
+			if gotEqual != tt.wantEqual {
+				t.Errorf("setHTTPRouteBackendWeights() equal = %v, want %v", gotEqual, tt.wantEqual)
+			}
+			if diff := cmp.Diff(tt.route, tt.want); diff != "" {
+				t.Errorf("setHTTPRouteBackendWeights() route = %v, want %v, diff %s", tt.route, tt.want, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// optimizerWebhookMaxBytes is the maximum size of a response from the external optimizer.
+const optimizerWebhookMaxBytes = 1 << 20
+
+// newOptimizeRequest returns an OptimizeRequest for the federated object.
+func newOptimizeRequest(typ v1beta1.OptimizeType, clusters []string, obj interface{}, replicas int32, requests corev1.ResourceList) (*v1beta1.OptimizeRequest, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if clusters == nil {
+		clusters = []string{}
+	}
+	req := &v1beta1.OptimizeRequest{
+		Type:     typ,
+		Clusters: clusters,
+		Object:   runtime.RawExtension{Raw: raw},
+		Replicas: replicas,
+		Requests: requests,
+	}
+	req.APIVersion = v1beta1.GroupVersion.String()
+	req.Kind = v1beta1.OptimizeRequestKind
+	return req, nil
+}
+
+// callOptimizerWebhook POSTs the request to the external optimizer and returns the validated response.
+func callOptimizerWebhook(ctx context.Context, wh *v1beta1.OptimizerWebhook, req *v1beta1.OptimizeRequest) (*v1beta1.OptimizeResponse, error) {
+	hc, err := defaultOptimizerWebhookClients.get(wh)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := v1beta1.DefaultOptimizerWebhookTimeout
+	if wh.Timeout != nil && wh.Timeout.Duration > 0 {
+		timeout = wh.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpResp, err := hc.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("POST %s: unexpected status %s", wh.URL, httpResp.Status)
+	}
+
+	resp := &v1beta1.OptimizeResponse{}
+	if err := json.NewDecoder(io.LimitReader(httpResp.Body, optimizerWebhookMaxBytes)).Decode(resp); err != nil {
+		return nil, fmt.Errorf("POST %s: %w", wh.URL, err)
+	}
+	if err := validateOptimizeResponse(resp, req.Clusters); err != nil {
+		return nil, fmt.Errorf("POST %s: %w", wh.URL, err)
+	}
+	return resp, nil
+}
+
+// optimizerWebhookClients caches HTTP clients for external optimizers per webhook URL,
+// so that connections are reused across reconciliations.
+type optimizerWebhookClients struct {
+	mu sync.Mutex
+	m  map[string]*optimizerWebhookClient
+}
+
+// optimizerWebhookClient is an HTTP client built with the TLS settings identified by fingerprint,
+// so that it is rebuilt when caBundle or insecureSkipVerify is changed.
+type optimizerWebhookClient struct {
+	client      *http.Client
+	transport   *http.Transport
+	fingerprint string
+}
+
+func newOptimizerWebhookClients() *optimizerWebhookClients {
+	return &optimizerWebhookClients{m: map[string]*optimizerWebhookClient{}}
+}
+
+// defaultOptimizerWebhookClients is shared by all controllers in the manager.
+var defaultOptimizerWebhookClients = newOptimizerWebhookClients()
+
+// get returns the cached client for the webhook, or a new one if not cached or the TLS settings are changed.
+func (p *optimizerWebhookClients) get(wh *v1beta1.OptimizerWebhook) (*http.Client, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "insecure=%t\n", wh.InsecureSkipVerify)
+	h.Write(wh.CABundle)
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old, ok := p.m[wh.URL]
+	if ok && old.fingerprint == fingerprint {
+		return old.client, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: wh.InsecureSkipVerify,
+	}
+	if len(wh.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(wh.CABundle) {
+			return nil, fmt.Errorf("caBundle contains no valid PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if ok {
+		old.transport.CloseIdleConnections()
+	}
+	hc := &http.Client{Transport: transport}
+	p.m[wh.URL] = &optimizerWebhookClient{client: hc, transport: transport, fingerprint: fingerprint}
+	return hc, nil
+}
+
+// validateOptimizeResponse checks that the response is an OptimizeResponse of the supported version
+// and only contains valid preferences for the candidate clusters.
+func validateOptimizeResponse(resp *v1beta1.OptimizeResponse, clusters []string) error {
+	if resp.APIVersion != v1beta1.GroupVersion.String() || resp.Kind != v1beta1.OptimizeResponseKind {
+		return fmt.Errorf("unsupported response %s %s, want %s %s",
+			resp.APIVersion, resp.Kind, v1beta1.GroupVersion.String(), v1beta1.OptimizeResponseKind)
+	}
+	candidates := make(map[string]struct{}, len(clusters))
+	for _, c := range clusters {
+		candidates[c] = struct{}{}
+	}
+	for k, v := range resp.Clusters {
+		if _, ok := candidates[k]; !ok {
+			return fmt.Errorf("cluster %s is not a candidate", k)
+		}
+		if v.Weight < 0 {
+			return fmt.Errorf("clusters[%s].weight must not be negative", k)
+		}
+		if v.MinReplicas < 0 {
+			return fmt.Errorf("clusters[%s].minReplicas must not be negative", k)
+		}
+		if v.MaxReplicas != nil && *v.MaxReplicas < v.MinReplicas {
+			return fmt.Errorf("clusters[%s].maxReplicas must not be less than minReplicas", k)
+		}
+	}
+	return nil
+}
+
+// intersectReplicaBounds returns the intersection of the bounds.
+// If they do not overlap, the max is raised to the min so that the min is still respected.
+func intersectReplicaBounds(a, b v1beta1.ReplicaBounds) v1beta1.ReplicaBounds {
+	out := v1beta1.ReplicaBounds{MinReplicas: a.MinReplicas, MaxReplicas: a.MaxReplicas}
+	if b.MinReplicas > out.MinReplicas {
+		out.MinReplicas = b.MinReplicas
+	}
+	if b.MaxReplicas != nil && (out.MaxReplicas == nil || *b.MaxReplicas < *out.MaxReplicas) {
+		out.MaxReplicas = b.MaxReplicas
+	}
+	if out.MaxReplicas != nil && *out.MaxReplicas < out.MinReplicas {
+		max := out.MinReplicas
+		out.MaxReplicas = &max
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_rspOptimizeFnWebhook(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req v1beta1.OptimizeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Kind != v1beta1.OptimizeRequestKind || req.Type != v1beta1.OptimizeTypeScheduling {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := `{"apiVersion": "waofed.bitmedia.co.jp/v1beta1", "kind": "OptimizeResponse", "clusters": {"c1": {"weight": 3, "maxReplicas": 5}, "c2": {"weight": 1}}}`
+		switch r.URL.Path {
+		case "/ok":
+		case "/unknown_cluster":
+			resp = `{"apiVersion": "waofed.bitmedia.co.jp/v1beta1", "kind": "OptimizeResponse", "clusters": {"c9": {"weight": 1}}}`
+		case "/wrong_kind":
+			resp = `{"apiVersion": "waofed.bitmedia.co.jp/v1beta1", "kind": "OptimizeRequest"}`
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer srv.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	fdeploy := &structuredFederatedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "fdeploy"},
+		Spec: &structuredFederatedDeploymentSpec{
+			Template: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(6)}},
+		},
+	}
+	clusters := []string{"c1", "c2", "c3"}
+	bounds := map[string]v1beta1.ReplicaBounds{"*": {MinReplicas: 1, MaxReplicas: pointer.Int64(4)}}
+
+	tests := []struct {
+		name    string
+		webhook *v1beta1.OptimizerWebhook
+		want    map[string]fedschedv1a1.ClusterPreferences
+		wantErr bool
+	}{
+		{"ok", &v1beta1.OptimizerWebhook{URL: srv.URL + "/ok", CABundle: caBundle}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {Weight: 3, MinReplicas: 1, MaxReplicas: pointer.Int64(4)},
+			"c2": {Weight: 1, MinReplicas: 1, MaxReplicas: pointer.Int64(4)},
+		}, false},
+		{"insecure", &v1beta1.OptimizerWebhook{URL: srv.URL + "/ok", InsecureSkipVerify: true}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {Weight: 3, MinReplicas: 1, MaxReplicas: pointer.Int64(4)},
+			"c2": {Weight: 1, MinReplicas: 1, MaxReplicas: pointer.Int64(4)},
+		}, false},
+		{"unknown_ca", &v1beta1.OptimizerWebhook{URL: srv.URL + "/ok"}, nil, true},
+		{"unknown_cluster", &v1beta1.OptimizerWebhook{URL: srv.URL + "/unknown_cluster", CABundle: caBundle}, nil, true},
+		{"wrong_kind", &v1beta1.OptimizerWebhook{URL: srv.URL + "/wrong_kind", CABundle: caBundle}, nil, true},
+		{"error_status", &v1beta1.OptimizerWebhook{URL: srv.URL + "/foo", CABundle: caBundle}, nil, true},
+		{"timeout", &v1beta1.OptimizerWebhook{URL: srv.URL + "/slow", CABundle: caBundle, Timeout: &metav1.Duration{Duration: 100 * time.Millisecond}}, nil, true},
+		{"no_webhook", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := &v1beta1.RSPOptimizerSettings{ReplicaBounds: bounds, Webhook: tt.webhook}
+			res, err := rspOptimizeFnWebhook(context.Background(), nil, "", clusters, settings, fdeploy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rspOptimizeFnWebhook() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got map[string]fedschedv1a1.ClusterPreferences
+			if res != nil {
+				got = res.clusters
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("rspOptimizeFnWebhook() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_validateOptimizeResponse(t *testing.T) {
+	typeMeta := metav1.TypeMeta{APIVersion: v1beta1.GroupVersion.String(), Kind: v1beta1.OptimizeResponseKind}
+	tests := []struct {
+		name    string
+		resp    *v1beta1.OptimizeResponse
+		wantErr bool
+	}{
+		{"ok", &v1beta1.OptimizeResponse{TypeMeta: typeMeta, Clusters: map[string]v1beta1.OptimizeClusterPreferences{
+			"c1": {Weight: 1, MinReplicas: 1, MaxReplicas: pointer.Int64(1)},
+		}}, false},
+		{"empty", &v1beta1.OptimizeResponse{TypeMeta: typeMeta}, false},
+		{"wrong_version", &v1beta1.OptimizeResponse{TypeMeta: metav1.TypeMeta{APIVersion: "waofed.bitmedia.co.jp/v2", Kind: v1beta1.OptimizeResponseKind}}, true},
+		{"not_candidate", &v1beta1.OptimizeResponse{TypeMeta: typeMeta, Clusters: map[string]v1beta1.OptimizeClusterPreferences{
+			"c9": {Weight: 1},
+		}}, true},
+		{"negative_weight", &v1beta1.OptimizeResponse{TypeMeta: typeMeta, Clusters: map[string]v1beta1.OptimizeClusterPreferences{
+			"c1": {Weight: -1},
+		}}, true},
+		{"max_less_than_min", &v1beta1.OptimizeResponse{TypeMeta: typeMeta, Clusters: map[string]v1beta1.OptimizeClusterPreferences{
+			"c1": {Weight: 1, MinReplicas: 2, MaxReplicas: pointer.Int64(1)},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOptimizeResponse(tt.resp, []string{"c1", "c2"}); (err != nil) != tt.wantErr {
+				t.Errorf("validateOptimizeResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_intersectReplicaBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b v1beta1.ReplicaBounds
+		want v1beta1.ReplicaBounds
+	}{
+		{"unbounded", v1beta1.ReplicaBounds{}, v1beta1.ReplicaBounds{}, v1beta1.ReplicaBounds{}},
+		{"tighter", v1beta1.ReplicaBounds{MinReplicas: 1, MaxReplicas: pointer.Int64(5)}, v1beta1.ReplicaBounds{MinReplicas: 2, MaxReplicas: pointer.Int64(3)},
+			v1beta1.ReplicaBounds{MinReplicas: 2, MaxReplicas: pointer.Int64(3)}},
+		{"max_from_b", v1beta1.ReplicaBounds{MinReplicas: 1}, v1beta1.ReplicaBounds{MaxReplicas: pointer.Int64(3)},
+			v1beta1.ReplicaBounds{MinReplicas: 1, MaxReplicas: pointer.Int64(3)}},
+		{"disjoint", v1beta1.ReplicaBounds{MinReplicas: 4}, v1beta1.ReplicaBounds{MaxReplicas: pointer.Int64(2)},
+			v1beta1.ReplicaBounds{MinReplicas: 4, MaxReplicas: pointer.Int64(4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := intersectReplicaBounds(tt.a, tt.b)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("intersectReplicaBounds() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_optimizerWebhookClients_get(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	p := newOptimizerWebhookClients()
+
+	wh := &v1beta1.OptimizerWebhook{URL: srv.URL + "/optimize", CABundle: caBundle}
+	hc1, err := p.get(wh)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	hc2, err := p.get(wh.DeepCopy())
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if hc1 != hc2 {
+		t.Errorf("get() returned a new client, want the cached one")
+	}
+
+	// change the TLS settings
+	wh.InsecureSkipVerify = true
+	hc3, err := p.get(wh)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if hc3 == hc1 {
+		t.Errorf("get() returned the cached client, want a new one for the changed insecureSkipVerify")
+	}
+	wh.CABundle = nil
+	hc4, err := p.get(wh)
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if hc4 == hc3 {
+		t.Errorf("get() returned the cached client, want a new one for the changed caBundle")
+	}
+
+	// another webhook
+	hc5, err := p.get(&v1beta1.OptimizerWebhook{URL: srv.URL + "/other", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if hc5 == hc4 {
+		t.Errorf("get() returned the client of another webhook")
+	}
+
+	if _, err := p.get(&v1beta1.OptimizerWebhook{URL: srv.URL, CABundle: []byte("foo")}); err == nil {
+		t.Errorf("get() error = nil for an invalid caBundle")
+	}
+}
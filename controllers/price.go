@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// currentElectricityPrices returns the electricity price of each cluster at the given time.
+// An explicit tariff takes precedence over "*", and a cluster without tariffs causes an error.
+// The returned slice has the same order as the given clusters.
+func currentElectricityPrices(tariffs map[string]v1beta1.TariffSchedule, clusters []string, now time.Time) ([]float64, error) {
+	out := make([]float64, len(clusters))
+	for i, c := range clusters {
+		tariff, ok := tariffs[c]
+		if !ok {
+			tariff, ok = tariffs["*"]
+		}
+		if !ok {
+			return nil, fmt.Errorf("no tariff for cluster %s", c)
+		}
+		price, err := tariff.PriceAt(now)
+		if err != nil {
+			return nil, fmt.Errorf("tariff for cluster %s: %w", c, err)
+		}
+		out[i] = price
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_currentElectricityPrices(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	tariffs := map[string]v1beta1.TariffSchedule{
+		"c1": {Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "0.1"}, {Start: "12:00", Price: "0.2"}}},
+		"*":  {TimeZone: "Asia/Tokyo", Bands: []v1beta1.TariffBand{{Start: "08:00", Price: "0.3"}, {Start: "20:00", Price: "0.4"}}},
+	}
+	tests := []struct {
+		name     string
+		tariffs  map[string]v1beta1.TariffSchedule
+		clusters []string
+		want     []float64
+		wantErr  bool
+	}{
+		{"explicit_and_wildcard", tariffs, []string{"c1", "c2"}, []float64{0.2, 0.4}, false},
+		{"missing", map[string]v1beta1.TariffSchedule{"c1": tariffs["c1"]}, []string{"c1", "c2"}, nil, true},
+		{"invalid", map[string]v1beta1.TariffSchedule{"*": {}}, []string{"c1"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := currentElectricityPrices(tt.tariffs, tt.clusters, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("currentElectricityPrices() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("currentElectricityPrices() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_rspReoptimizeAfter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	rr := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodRoundRobin)
+	price := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodPrice)
+	wao := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodWAO)
+	tariffs := map[string]v1beta1.TariffSchedule{
+		"c1": {Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "0.1"}, {Start: "14:00", Price: "0.2"}}},
+		"c2": {Bands: []v1beta1.TariffBand{{Start: "00:00", Price: "0.1"}, {Start: "13:00", Price: "0.2"}}},
+	}
+	tests := []struct {
+		name     string
+		settings *v1beta1.RSPOptimizerSettings
+		method   v1beta1.RSPOptimizerMethod
+		want     time.Duration
+	}{
+		{"none", &v1beta1.RSPOptimizerSettings{Method: &rr}, rr, 0},
+		{"interval", &v1beta1.RSPOptimizerSettings{Method: &rr, ReoptimizeInterval: &metav1.Duration{Duration: 10 * time.Minute}}, rr, 10 * time.Minute},
+		{"tariffs_ignored", &v1beta1.RSPOptimizerSettings{Method: &rr, Tariffs: tariffs}, rr, 0},
+		{"price", &v1beta1.RSPOptimizerSettings{Method: &price, Tariffs: tariffs}, price, time.Hour},
+		{"price_interval_first", &v1beta1.RSPOptimizerSettings{Method: &price, Tariffs: tariffs, ReoptimizeInterval: &metav1.Duration{Duration: 10 * time.Minute}}, price, 10 * time.Minute},
+		{"price_band_first", &v1beta1.RSPOptimizerSettings{Method: &price, Tariffs: tariffs, ReoptimizeInterval: &metav1.Duration{Duration: 2 * time.Hour}}, price, time.Hour},
+		{"price_failed", &v1beta1.RSPOptimizerSettings{Method: &price, FallbackMethods: []v1beta1.RSPOptimizerMethod{rr}, Tariffs: tariffs}, rr, 0},
+		{"price_fallback", &v1beta1.RSPOptimizerSettings{Method: &wao, FallbackMethods: []v1beta1.RSPOptimizerMethod{price}, Tariffs: tariffs}, price, time.Hour},
+		{"no_result", &v1beta1.RSPOptimizerSettings{Method: &price, Tariffs: tariffs}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rspReoptimizeAfter(tt.settings, tt.method, now); got != tt.want {
+				t.Errorf("rspReoptimizeAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// optimizeClusterWeightsForScaleUp optimizes the placement of the replicas added by scaling up,
+// keeping the current placement, for RSPs with rebalance disabled.
+// It returns nil if the object is not scaled up, that is the current weights should be kept.
+//
+// The returned weights are the current replicas plus the added replicas of each cluster,
+// so that KubeFed (which keeps the current replicas as rebalance is disabled) places the added replicas as optimized.
+func (r *RSPOptimizerReconciler) optimizeClusterWeightsForScaleUp(
+	ctx context.Context, fdeploy *structuredFederatedDeployment, wfc *v1beta1.WAOFedConfig, totalReplicas int32, current map[string]int64,
+) (*rspOptimizeResult, v1beta1.RSPOptimizerMethod, error) {
+	lg := log.FromContext(ctx)
+	settings := wfc.Spec.Scheduling.Optimizer
+
+	var currentTotal int64
+	for _, n := range current {
+		currentTotal += n
+	}
+	delta := int64(totalReplicas) - currentTotal
+	lg.Info("optimizeClusterWeightsForScaleUp", "current", current, "totalReplicas", totalReplicas, "delta", delta)
+	if delta <= 0 {
+		return nil, "", nil
+	}
+
+	// optimize the placement of the added replicas
+	deltaFdeploy := *fdeploy
+	deltaSpec := *fdeploy.Spec
+	deltaSpec.Template = fdeploy.Spec.Template.DeepCopy()
+	deltaSpec.Template.Spec.Replicas = pointer.Int32(int32(delta))
+	deltaFdeploy.Spec = &deltaSpec
+	deltaWfc := wfc.DeepCopy()
+	deltaWfc.Spec.Scheduling.Optimizer.ReplicaBounds = scaleUpReplicaBounds(settings, current)
+
+	res, method, err := r.optimizeClusterWeights(ctx, &deltaFdeploy, deltaWfc)
+	if err != nil {
+		return nil, "", err
+	}
+	added := distributeReplicas(clusterWeights(res.clusters), delta)
+
+	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(res.clusters))
+	for c := range res.clusters {
+		b := clusterReplicaBounds(settings, c)
+		cps[c] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: b.MinReplicas,
+			MaxReplicas: b.MaxReplicas,
+			Weight:      current[c] + added[c],
+		}
+	}
+	// NOTE: the costs of the method are for the added replicas, so do not return them
+	return &rspOptimizeResult{clusters: cps, excluded: res.excluded}, method, nil
+}
+
+// scaleUpReplicaBounds returns the replica bounds for the added replicas,
+// that is the bounds of each cluster minus the current replicas.
+func scaleUpReplicaBounds(settings *v1beta1.RSPOptimizerSettings, current map[string]int64) map[string]v1beta1.ReplicaBounds {
+	out := make(map[string]v1beta1.ReplicaBounds, len(settings.ReplicaBounds)+len(current))
+	for k, v := range settings.ReplicaBounds {
+		out[k] = v
+	}
+	for c, n := range current {
+		if n <= 0 {
+			continue
+		}
+		b := clusterReplicaBounds(settings, c)
+		min := b.MinReplicas - n
+		if min < 0 {
+			min = 0
+		}
+		var max *int64
+		if b.MaxReplicas != nil {
+			max = pointer.Int64(*b.MaxReplicas - n)
+			if *max < min {
+				*max = min
+			}
+		}
+		out[c] = v1beta1.ReplicaBounds{MinReplicas: min, MaxReplicas: max}
+	}
+	return out
+}
+
+// distributeReplicas distributes the replicas in proportion to the weights by the largest remainder method.
+// Ties are broken by the cluster names to make the result deterministic.
+func distributeReplicas(weights map[string]int64, replicas int64) map[string]int64 {
+	out := make(map[string]int64, len(weights))
+	var sum int64
+	names := make([]string, 0, len(weights))
+	for c, w := range weights {
+		if w > 0 {
+			sum += w
+			names = append(names, c)
+		}
+	}
+	if sum == 0 || replicas <= 0 {
+		return out
+	}
+	sort.Strings(names)
+
+	remainders := make(map[string]int64, len(names))
+	rest := replicas
+	for _, c := range names {
+		out[c] = replicas * weights[c] / sum
+		remainders[c] = replicas * weights[c] % sum
+		rest -= out[c]
+	}
+	sort.SliceStable(names, func(i, j int) bool { return remainders[names[i]] > remainders[names[j]] })
+	for i := int64(0); i < rest; i++ {
+		out[names[i]]++
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_RSPOptimizerReconciler_optimizeClusterWeightsForScaleUp(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = fedcorev1b1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		helperReadyKubeFedCluster("c1", nil),
+		helperReadyKubeFedCluster("c2", nil),
+	).Build()
+	r := &RSPOptimizerReconciler{Client: c, Scheme: scheme}
+
+	fdeploy := &structuredFederatedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "fdeploy"},
+		Spec: &structuredFederatedDeploymentSpec{
+			Template: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(7)}},
+			Placement: &util.GenericPlacementFields{
+				Clusters: []util.GenericClusterReference{{Name: "c1"}, {Name: "c2"}},
+			},
+		},
+	}
+	rr := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodRoundRobin)
+	wfc := &v1beta1.WAOFedConfig{Spec: v1beta1.WAOFedConfigSpec{
+		KubeFedNamespace: "kube-federation-system",
+		Scheduling: &v1beta1.SchedulingSettings{Optimizer: &v1beta1.RSPOptimizerSettings{
+			Method:        &rr,
+			Rebalance:     pointer.Bool(false),
+			ReplicaBounds: map[string]v1beta1.ReplicaBounds{"*": {MinReplicas: 1}},
+		}},
+	}}
+
+	tests := []struct {
+		name          string
+		totalReplicas int32
+		current       map[string]int64
+		want          map[string]fedschedv1a1.ClusterPreferences
+	}{
+		{"scale_up", 7, map[string]int64{"c1": 3, "c2": 1}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {Weight: 5, MinReplicas: 1},
+			"c2": {Weight: 2, MinReplicas: 1},
+		}},
+		{"new_cluster", 8, map[string]int64{"c1": 6}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {Weight: 7, MinReplicas: 1},
+			"c2": {Weight: 1, MinReplicas: 1},
+		}},
+		{"unchanged", 4, map[string]int64{"c1": 3, "c2": 1}, nil},
+		{"scale_down", 2, map[string]int64{"c1": 3, "c2": 1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, method, err := r.optimizeClusterWeightsForScaleUp(context.Background(), fdeploy, wfc, tt.totalReplicas, tt.current)
+			if err != nil {
+				t.Errorf("optimizeClusterWeightsForScaleUp() error = %v", err)
+				return
+			}
+			var got map[string]fedschedv1a1.ClusterPreferences
+			if res != nil {
+				got = res.clusters
+				if method != rr {
+					t.Errorf("optimizeClusterWeightsForScaleUp() method = %v, want %v", method, rr)
+				}
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("optimizeClusterWeightsForScaleUp() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+	// the original objects must not be modified
+	if *fdeploy.Spec.Template.Spec.Replicas != 7 || wfc.Spec.Scheduling.Optimizer.ReplicaBounds["c1"] != (v1beta1.ReplicaBounds{}) {
+		t.Errorf("optimizeClusterWeightsForScaleUp() modified the arguments")
+	}
+}
+
+func Test_scaleUpReplicaBounds(t *testing.T) {
+	settings := &v1beta1.RSPOptimizerSettings{ReplicaBounds: map[string]v1beta1.ReplicaBounds{
+		"*":  {MinReplicas: 2},
+		"c1": {MinReplicas: 1, MaxReplicas: pointer.Int64(4)},
+	}}
+	current := map[string]int64{"c1": 3, "c2": 1, "c3": 5, "c4": 0}
+	want := map[string]v1beta1.ReplicaBounds{
+		"*":  {MinReplicas: 2},
+		"c1": {MinReplicas: 0, MaxReplicas: pointer.Int64(1)},
+		"c2": {MinReplicas: 1},
+		"c3": {MinReplicas: 0},
+	}
+	got := scaleUpReplicaBounds(settings, current)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("scaleUpReplicaBounds() = %v, want %v, diff %s", got, want, diff)
+	}
+
+	// max is never less than min
+	got = scaleUpReplicaBounds(settings, map[string]int64{"c1": 5})
+	if b := got["c1"]; b.MinReplicas != 0 || b.MaxReplicas == nil || *b.MaxReplicas != 0 {
+		t.Errorf("scaleUpReplicaBounds() c1 = %v, want min 0 max 0", b)
+	}
+}
+
+func Test_distributeReplicas(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  map[string]int64
+		replicas int64
+		want     map[string]int64
+	}{
+		{"even", map[string]int64{"c1": 1, "c2": 1}, 4, map[string]int64{"c1": 2, "c2": 2}},
+		{"proportional", map[string]int64{"c1": 3, "c2": 1}, 8, map[string]int64{"c1": 6, "c2": 2}},
+		{"largest_remainder", map[string]int64{"c1": 1, "c2": 2, "c3": 2}, 3, map[string]int64{"c1": 1, "c2": 1, "c3": 1}},
+		{"tie_by_name", map[string]int64{"c2": 1, "c1": 1}, 1, map[string]int64{"c1": 1, "c2": 0}},
+		{"zero_weight", map[string]int64{"c1": 0, "c2": 1}, 3, map[string]int64{"c2": 3}},
+		{"all_zero", map[string]int64{"c1": 0, "c2": 0}, 3, map[string]int64{}},
+		{"no_replicas", map[string]int64{"c1": 1}, 0, map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := distributeReplicas(tt.weights, tt.replicas)
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("distributeReplicas() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
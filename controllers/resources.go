@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// podResourceModel specifies how a resource of pods is counted.
+type podResourceModel struct {
+	name   corev1.ResourceName
+	format resource.Format
+	// milli is true if the resource is counted in milli units (e.g. cpu).
+	milli bool
+	// nonZero is the amount counted for a container that neither requests nor limits the resource
+	// when estimating power consumption, so that such pods (e.g. BestEffort pods) are not regarded as free.
+	//
+	// Ref. k8s.io/kubernetes/pkg/scheduler/util.GetNonzeroRequests
+	nonZero resource.Quantity
+}
+
+// podResourceModels are the resources of pods counted by optimizers.
+// Extended resources (e.g. accelerators) can be counted by adding models.
+var podResourceModels = []podResourceModel{
+	{name: corev1.ResourceCPU, format: resource.DecimalSI, milli: true, nonZero: resource.MustParse("100m")},
+	{name: corev1.ResourceMemory, format: resource.BinarySI, nonZero: resource.MustParse("200Mi")},
+}
+
+// podResources returns the effective resources of the pod for podResourceModels,
+// that is the larger of the sum of the containers and the max of the init containers, plus the overhead.
+//
+// A container uses the limits for the resources it does not request, as the API server defaults the requests to the limits.
+// If nonZero is true, a container that neither requests nor limits a resource counts the nonZero amount of the model.
+func podResources(spec *corev1.PodSpec, nonZero bool) corev1.ResourceList {
+	out := make(corev1.ResourceList, len(podResourceModels))
+	for _, m := range podResourceModels {
+		sum := resource.Quantity{}
+		for i := range spec.Containers {
+			sum.Add(containerResource(&spec.Containers[i], m, nonZero))
+		}
+		for i := range spec.InitContainers {
+			if q := containerResource(&spec.InitContainers[i], m, nonZero); q.Cmp(sum) > 0 {
+				sum = q
+			}
+		}
+		if q, ok := spec.Overhead[m.name]; ok {
+			sum.Add(q)
+		}
+		out[m.name] = sum
+	}
+	return out
+}
+
+// containerResource returns the effective resource of the container (Ref. podResources).
+func containerResource(c *corev1.Container, m podResourceModel, nonZero bool) resource.Quantity {
+	if q, ok := c.Resources.Requests[m.name]; ok {
+		return q.DeepCopy()
+	}
+	if q, ok := c.Resources.Limits[m.name]; ok {
+		return q.DeepCopy()
+	}
+	if nonZero {
+		return m.nonZero.DeepCopy()
+	}
+	return resource.Quantity{}
+}
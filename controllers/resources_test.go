@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func Test_podResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       *corev1.PodSpec
+		nonZero    bool
+		wantCPU    string
+		wantMemory string
+	}{
+		{"empty", &corev1.PodSpec{}, false, "0", "0"},
+		{"containers", &corev1.PodSpec{Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: helperResourceList("100m", "100Mi")}},
+			{Resources: corev1.ResourceRequirements{Requests: helperResourceList("200m", "")}},
+		}}, false, "300m", "100Mi"},
+		{"init_containers", &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: helperResourceList("100m", "100Mi")}},
+			},
+			InitContainers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: helperResourceList("500m", "50Mi")}},
+			},
+		}, false, "500m", "100Mi"},
+		{"overhead", &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: helperResourceList("100m", "100Mi")}},
+			},
+			Overhead: helperResourceList("10m", "10Mi"),
+		}, false, "110m", "110Mi"},
+		{"limits", &corev1.PodSpec{Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: helperResourceList("100m", ""), Limits: helperResourceList("1", "1Gi")}},
+			{Resources: corev1.ResourceRequirements{Limits: helperResourceList("200m", "100Mi")}},
+		}}, false, "300m", "1124Mi"},
+		{"no_requests", &corev1.PodSpec{Containers: []corev1.Container{
+			{},
+			{Resources: corev1.ResourceRequirements{Requests: helperResourceList("", "100Mi")}},
+		}}, false, "0", "100Mi"},
+		{"no_requests_nonzero", &corev1.PodSpec{Containers: []corev1.Container{
+			{},
+			{Resources: corev1.ResourceRequirements{Requests: helperResourceList("", "100Mi")}},
+		}}, true, "200m", "300Mi"},
+		{"init_containers_nonzero", &corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Resources: corev1.ResourceRequirements{Requests: helperResourceList("50m", "")}},
+			},
+			InitContainers: []corev1.Container{{}},
+		}, true, "100m", "200Mi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := podResources(tt.spec, tt.nonZero)
+			if q := resource.MustParse(tt.wantCPU); got.Cpu().Cmp(q) != 0 {
+				t.Errorf("podResources() cpu = %v, want %v", got.Cpu(), tt.wantCPU)
+			}
+			if q := resource.MustParse(tt.wantMemory); got.Memory().Cmp(q) != 0 {
+				t.Errorf("podResources() memory = %v, want %v", got.Memory(), tt.wantMemory)
+			}
+		})
+	}
+}
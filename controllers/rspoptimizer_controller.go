@@ -1,22 +1,30 @@
 package controllers
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"math"
 	"sync"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
 	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+	fedctrlutil "sigs.k8s.io/kubefed/pkg/controller/util"
 
-	"github.com/Nedopro2022/wao-estimator/pkg/estimator"
 	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
 )
 
@@ -28,23 +36,209 @@ type RSPOptimizerReconciler struct {
 	ControllerName string
 }
 
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get
 //+kubebuilder:rbac:groups=core.kubefed.io,resources=kubefedclusters,verbs=get;list;watch
 //+kubebuilder:rbac:groups=types.kubefed.io,resources=federateddeployments,verbs=get;list;watch
+//+kubebuilder:rbac:groups=types.kubefed.io,resources=federatedreplicasets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=scheduling.kubefed.io,resources=replicaschedulingpreferences,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=waofedconfigs,verbs=get;list;watch
 
 // SetupWithManager sets up the controller with the Manager.
+//
+// A controller is set up for each kind in replicaSchedulingGVKs,
+// and kinds other than FederatedDeployment are skipped if not installed (e.g. not enabled in KubeFed).
 func (r *RSPOptimizerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	r.ControllerName = v1beta1.OperatorName + "-rspoptimizer-controller"
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(newUnstructuredFederatedDeployment()).
-		Owns(&fedschedv1a1.ReplicaSchedulingPreference{}).
-		Complete(r)
+	lg := mgr.GetLogger().WithName(r.ControllerName)
+
+	for _, gvk := range replicaSchedulingGVKs {
+		if gvk != federatedDeploymentGVK {
+			if _, err := mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+				if meta.IsNoMatchError(err) {
+					lg.Info("skip as the kind is not installed", "gvk", gvk)
+					continue
+				}
+				return err
+			}
+		}
+
+		kr := &rspOptimizerKindReconciler{RSPOptimizerReconciler: r, gvk: gvk}
+		if err := ctrl.NewControllerManagedBy(mgr).
+			For(newUnstructuredFederatedObject(gvk)).
+			Owns(&fedschedv1a1.ReplicaSchedulingPreference{}).
+			Watches(
+				&source.Kind{Type: &fedcorev1b1.KubeFedCluster{}},
+				handler.EnqueueRequestsFromMapFunc(kr.mapKubeFedClusterToFederatedDeployments),
+				builder.WithPredicates(kubeFedClusterPredicate),
+			).
+			Watches(
+				&source.Kind{Type: &corev1.Namespace{}},
+				handler.EnqueueRequestsFromMapFunc(kr.mapNamespaceToFederatedDeployments),
+				builder.WithPredicates(namespacePredicate),
+			).
+			Watches(
+				&source.Kind{Type: &v1beta1.WAOFedConfig{}},
+				handler.EnqueueRequestsFromMapFunc(kr.mapWAOFedConfigToFederatedDeployments),
+				builder.WithPredicates(waoFedConfigPredicate),
+			).
+			Complete(kr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rspOptimizerKindReconciler reconciles federated objects of a kind in replicaSchedulingGVKs.
+type rspOptimizerKindReconciler struct {
+	*RSPOptimizerReconciler
+
+	gvk schema.GroupVersionKind
+}
+
+// mapKubeFedClusterToFederatedDeployments returns requests for FederatedDeployments (or other kinds in replicaSchedulingGVKs)
+// that are selected by WAOFedConfig and may be placed on the KubeFedCluster.
+func (r *rspOptimizerKindReconciler) mapKubeFedClusterToFederatedDeployments(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("kubefedcluster", client.ObjectKeyFromObject(o))
+
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+	if wfc.Spec.Scheduling == nil || o.GetNamespace() != wfc.Spec.KubeFedNamespace {
+		return nil
+	}
+
+	var dp *v1beta1.DefaultPlacement
+	if wfc.Spec.Scheduling.Optimizer != nil {
+		dp = wfc.Spec.Scheduling.Optimizer.DefaultPlacement
+	}
+
+	items, err := listFederatedObjects(ctx, r.Client, r.gvk)
+	if err != nil {
+		lg.Error(err, "unable to list federated objects", "gvk", r.gvk)
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range items {
+		fdeploy, err := convertToStructuredFederatedDeployment(&items[i])
+		if err != nil {
+			continue
+		}
+		selected, err := matchResourceSelector(ctx, r.Client, wfc.Spec.Scheduling.Selector, fdeploy)
+		if err != nil {
+			lg.Error(err, "unable to match resource selector", "obj", client.ObjectKeyFromObject(&items[i]))
+			continue
+		}
+		if !selected {
+			continue
+		}
+		if !placementMayInclude(placementWithDefault(fdeploy.Spec.Placement, dp), o.GetName()) {
+			continue
+		}
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&items[i])})
+	}
+	lg.Info("KubeFedCluster changed", "requests", len(reqs))
+	return reqs
+}
+
+// mapWAOFedConfigToFederatedDeployments returns requests for FederatedDeployments (or other kinds in replicaSchedulingGVKs)
+// that are selected by WAOFedConfig and FederatedDeployments that own RSPs created by RSPOptimizer (so that the RSPs can be deleted if no longer selected).
+func (r *rspOptimizerKindReconciler) mapWAOFedConfigToFederatedDeployments(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("waofedconfig", client.ObjectKeyFromObject(o))
+
+	if o.GetName() != v1beta1.WAOFedConfigName {
+		return nil
+	}
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+
+	reqs := map[types.NamespacedName]struct{}{}
+
+	// RSPs created by RSPOptimizer have the same namespace and name as the owner FederatedDeployment
+	prefs := &fedschedv1a1.ReplicaSchedulingPreferenceList{}
+	if err := r.List(ctx, prefs, client.MatchingLabels{"app.kubernetes.io/created-by": r.ControllerName}); err != nil {
+		lg.Error(err, "unable to list RSPs")
+		return nil
+	}
+	for _, pref := range prefs.Items {
+		if pref.Spec.TargetKind != r.gvk.Kind {
+			continue
+		}
+		reqs[client.ObjectKeyFromObject(&pref)] = struct{}{}
+	}
+
+	if wfc.Spec.Scheduling != nil {
+		items, err := listFederatedObjects(ctx, r.Client, r.gvk)
+		if err != nil {
+			lg.Error(err, "unable to list federated objects", "gvk", r.gvk)
+			return nil
+		}
+		for i := range items {
+			fdeploy, err := convertToStructuredFederatedDeployment(&items[i])
+			if err != nil {
+				continue
+			}
+			selected, err := matchResourceSelector(ctx, r.Client, wfc.Spec.Scheduling.Selector, fdeploy)
+			if err != nil {
+				lg.Error(err, "unable to match resource selector", "obj", client.ObjectKeyFromObject(&items[i]))
+				continue
+			}
+			if selected {
+				reqs[client.ObjectKeyFromObject(&items[i])] = struct{}{}
+			}
+		}
+	}
+
+	lg.Info("WAOFedConfig changed", "requests", len(reqs))
+	return toRequests(reqs)
+}
+
+// mapNamespaceToFederatedDeployments returns requests for all FederatedDeployments (or other kinds in replicaSchedulingGVKs) in the Namespace
+// if WAOFedConfig selects them by the namespace labels.
+func (r *rspOptimizerKindReconciler) mapNamespaceToFederatedDeployments(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("namespace", o.GetName())
+
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+	if wfc.Spec.Scheduling == nil || wfc.Spec.Scheduling.Selector.NamespaceSelector == nil {
+		return nil
+	}
+
+	// NOTE: unselected ones are also requested so that their RSPs can be deleted
+	items, err := listFederatedObjects(ctx, r.Client, r.gvk, client.InNamespace(o.GetName()))
+	if err != nil {
+		lg.Error(err, "unable to list federated objects", "gvk", r.gvk)
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(items))
+	for i := range items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&items[i])})
+	}
+	lg.Info("Namespace changed", "requests", len(reqs))
+	return reqs
 }
 
 // Reconcile moves the current state of the cluster closer to the desired state.
-func (r *RSPOptimizerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *rspOptimizerKindReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	lg := log.FromContext(ctx)
 	lg.Info("Reconcile")
 
@@ -61,50 +255,76 @@ func (r *RSPOptimizerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 	if wfc.Spec.Scheduling == nil {
-		lg.Info("WAOFedConfig spec.scheduling is nil, drop the request")
-		return ctrl.Result{}, nil
+		// RSPs created by RSPOptimizer should be deleted as no FederatedDeployment is selected
+		lg.Info("WAOFedConfig spec.scheduling is nil")
 	}
 
-	// get FederatedDeployment
-	fdep := newUnstructuredFederatedDeployment()
+	// get FederatedDeployment (or other kinds in replicaSchedulingGVKs)
+	fdep := newUnstructuredFederatedObject(r.gvk)
 	err = r.Get(ctx, req.NamespacedName, fdep)
 	if errors.IsNotFound(err) {
-		lg.Info("FederatedDeployment is already deleted")
+		lg.Info(fmt.Sprintf("%s is already deleted", r.gvk.Kind))
 		return ctrl.Result{}, nil
 	}
 	if err != nil {
-		lg.Error(err, "unable to get FederatedDeployment")
+		lg.Error(err, fmt.Sprintf("unable to get %s", r.gvk.Kind))
 		return ctrl.Result{}, err
 	}
 	fdeploy, err := convertToStructuredFederatedDeployment(fdep)
 	if err != nil {
-		lg.Error(err, "unable to convert FederatedDeployment")
+		lg.Error(err, fmt.Sprintf("unable to convert %s", r.gvk.Kind))
 		return ctrl.Result{}, err
 	}
 
+	// check whether the FederatedDeployment is selected
+	selected := false
+	if wfc.Spec.Scheduling != nil {
+		selected, err = matchResourceSelector(ctx, r.Client, wfc.Spec.Scheduling.Selector, fdeploy)
+		if err != nil {
+			lg.Error(err, "unable to match resource selector")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// merge per-object optimizer overrides into the settings
+	// NOTE: wfc is fetched for this request only, so it is safe to overwrite
+	if selected {
+		settings, err := wfc.Spec.Scheduling.Optimizer.WithOverrides(fdeploy.Annotations)
+		if err != nil {
+			lg.Error(err, "invalid RSPOptimizer override annotations, use WAOFedConfig settings")
+		} else {
+			wfc.Spec.Scheduling.Optimizer = settings
+		}
+	}
+
 	// reconcile RSP
-	if err := r.reconcileRSP(ctx, fdeploy, wfc); err != nil {
+	deferred, method, err := r.reconcileRSP(ctx, fdeploy, wfc, selected)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// requeue to re-optimize cluster weights periodically, or to apply the weights deferred for stabilization
+	if selected {
+		d := rspReoptimizeAfter(wfc.Spec.Scheduling.Optimizer, method, time.Now())
+		if deferred > 0 && (d == 0 || deferred < d) {
+			d = deferred
+		}
+		if d > 0 {
+			lg.Info("requeue to re-optimize", "after", d)
+			return ctrl.Result{RequeueAfter: d}, nil
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
 func (r *RSPOptimizerReconciler) reconcileRSP(
-	ctx context.Context, fdeploy *structuredFederatedDeployment, wfc *v1beta1.WAOFedConfig,
-) error {
+	ctx context.Context, fdeploy *structuredFederatedDeployment, wfc *v1beta1.WAOFedConfig, selected bool,
+) (time.Duration, v1beta1.RSPOptimizerMethod, error) {
 	lg := log.FromContext(ctx)
 	lg.Info("reconcileRSP")
 
-	skip := true
-	if *wfc.Spec.Scheduling.Selector.Any {
-		// check selector.any
-		skip = false
-	} else if _, ok := fdeploy.GetAnnotations()[*wfc.Spec.Scheduling.Selector.HasAnnotation]; ok {
-		// check RSPOptimizer annotation exists in the FederatedDeployment
-		// currently the value is ignored
-		skip = false
-	}
+	skip := !selected
 
 	if skip {
 		// delete the associated RSP if no annotation in the FederatedDeployment
@@ -124,11 +344,11 @@ func (r *RSPOptimizerReconciler) reconcileRSP(
 		err := r.Get(ctx, client.ObjectKeyFromObject(rsp), rsp)
 		if errors.IsNotFound(err) {
 			lg.Info("RSP is already deleted")
-			return nil
+			return 0, "", nil
 		}
 		if err != nil {
 			lg.Error(err, "unable to get RSP")
-			return err
+			return 0, "", err
 		}
 		// check OwnerReference
 		ctrlRef := metav1.GetControllerOf(rsp)
@@ -143,39 +363,89 @@ func (r *RSPOptimizerReconciler) reconcileRSP(
 			err := r.Delete(ctx, rsp)
 			if errors.IsNotFound(err) {
 				lg.Info("RSP is already deleted")
-				return nil
+				return 0, "", nil
 			}
 			if err != nil {
 				lg.Error(err, "unable to delete RSP")
-				return err
+				return 0, "", err
 			}
 		}
-		return nil
+		return 0, "", nil
 	} else {
 		// apply RSP if !skip
+		if fdeploy.Spec == nil || fdeploy.Spec.Template == nil {
+			// NOTE: the federated object webhook may not reject it as failurePolicy=ignore
+			lg.Info("skip as spec.template is not specified")
+			return 0, "", nil
+		}
 		rsp := &fedschedv1a1.ReplicaSchedulingPreference{}
 		rsp.SetNamespace(fdeploy.Namespace)
 		rsp.SetName(fdeploy.Name)
+		var requeueAfter time.Duration
+		var used v1beta1.RSPOptimizerMethod
 		op, err := ctrl.CreateOrUpdate(ctx, r.Client, rsp, func() error {
 			// set labels
 			rsp.Labels = map[string]string{
 				"app.kubernetes.io/created-by": r.ControllerName,
 			}
+			if rsp.Annotations == nil {
+				rsp.Annotations = map[string]string{}
+			}
+			// keep the current state for stabilization
+			lastClusters := rsp.Spec.Clusters
+			lastTotalReplicas := rsp.Spec.TotalReplicas
+			last := getRSPAppliedWeights(rsp.Annotations)
 			// set RSP spec except clusters
+			settings := wfc.Spec.Scheduling.Optimizer
+			rebalance := settings.Rebalance == nil || *settings.Rebalance
 			rsp.Spec = fedschedv1a1.ReplicaSchedulingPreferenceSpec{
 				TargetKind:                   fdeploy.Kind,
-				TotalReplicas:                *fdeploy.Spec.Template.Spec.Replicas,
-				Rebalance:                    true,
-				IntersectWithClusterSelector: true,
+				TotalReplicas:                fdeploy.templateReplicas(),
+				Rebalance:                    rebalance,
+				IntersectWithClusterSelector: settings.IntersectWithClusterSelector == nil || *settings.IntersectWithClusterSelector,
 				Clusters:                     nil,
 			}
 			// set RSP clusters
-			lg.Info("optimize cluster weights", "method", wfc.Spec.Scheduling.Optimizer.Method)
-			clusters, err := r.optimizeClusterWeights(ctx, fdeploy, wfc)
-			if err != nil {
-				return err
+			lg.Info("optimize cluster weights", "method", settings.Method, "rebalance", rebalance)
+			var res *rspOptimizeResult
+			var method v1beta1.RSPOptimizerMethod
+			var err error
+			if current := fdeploy.currentReplicas(); !rebalance && len(lastClusters) > 0 && len(current) > 0 {
+				// keep the current placement and only optimize the placement of the added replicas
+				res, method, err = r.optimizeClusterWeightsForScaleUp(ctx, fdeploy, wfc, rsp.Spec.TotalReplicas, current)
+				if err != nil {
+					return err
+				}
+			} else {
+				res, method, err = r.optimizeClusterWeights(ctx, fdeploy, wfc)
+				if err != nil {
+					return err
+				}
+			}
+			if res != nil {
+				used = method
+			}
+			now := time.Now()
+			if res == nil {
+				lg.Info("keep the current cluster weights as rebalance is disabled and not scaled up")
+				rsp.Spec.Clusters = lastClusters
+			} else if apply, after := stabilizeRSPClusters(settings.Stabilization, last, lastTotalReplicas, rsp.Spec.TotalReplicas, res, now); !apply {
+				lg.Info("keep the current cluster weights for stabilization", "weights", res.clusters, "requeueAfter", after)
+				rsp.Spec.Clusters = lastClusters
+				requeueAfter = after
+			} else {
+				rsp.Spec.Clusters = res.clusters
+				// record the method used and the applied weights
+				rsp.Annotations[v1beta1.RSPOptimizerMethodUsedAnnotation] = string(method)
+				if err := setRSPExcludedClusters(rsp.Annotations, res.excluded); err != nil {
+					return err
+				}
+				if weights := clusterWeights(res.clusters); last == nil || !sameWeights(last.weights, weights) {
+					if err := setRSPAppliedWeights(rsp.Annotations, weights, now); err != nil {
+						return err
+					}
+				}
 			}
-			rsp.Spec.Clusters = clusters
 			// set OwnerReference
 			//
 			// HACK: ctrl.SetControllerReference requires both owner and controlled to have scheme registration,
@@ -192,121 +462,186 @@ func (r *RSPOptimizerReconciler) reconcileRSP(
 		})
 		if err != nil {
 			lg.Error(err, "unable to create or update RSP")
+			return 0, "", err
 		}
 		lg.Info("RSP operated", "op", op)
+		return requeueAfter, used, nil
 	}
-
-	return nil
 }
 
 func (r *RSPOptimizerReconciler) optimizeClusterWeights(
 	ctx context.Context, fdeploy *structuredFederatedDeployment, wfc *v1beta1.WAOFedConfig,
-) (map[string]fedschedv1a1.ClusterPreferences, error) {
+) (*rspOptimizeResult, v1beta1.RSPOptimizerMethod, error) {
 	lg := log.FromContext(ctx)
 	lg.Info("optimizeClusterWeights", "wfc", wfc, "fdeploy", fdeploy)
 
 	// get clusters
 	//   if has placement.clusters field, the specified clusters should be candidates
 	//   if only has placement.clusterSelector field, the selected clusters should be candidates
-	//   if no placement field, no (or all) clusters should be candidates (consider making it configurable)
-	if fdeploy.Spec.Placement == nil || (fdeploy.Spec.Placement.Clusters == nil && fdeploy.Spec.Placement.ClusterSelector == nil) {
+	//   if no placement field, the clusters specified by defaultPlacement (no clusters by default) should be candidates
+	placement := placementWithDefault(fdeploy.Spec.Placement, wfc.Spec.Scheduling.Optimizer.DefaultPlacement)
+	if placement == nil {
 		lg.Info("no scheduling as spec.placement == nil", "spec.placement", fdeploy.Spec.Placement)
-		return map[string]fedschedv1a1.ClusterPreferences{}, nil
-	}
-
-	var candidates []string
-	if fdeploy.Spec.Placement.Clusters != nil {
-		// placement.clusters is specified
-		lg.Info("placement.clusters found", "fdeploy", fdeploy.Spec.Placement.Clusters)
-		for _, c := range fdeploy.Spec.Placement.Clusters {
-			candidates = append(candidates, c.Name)
-		}
-	} else { // fdeploy.Spec.Placement.ClusterSelector != nil
-		// placement.clusterSelector is specified
-		lg.Info("placement.clusterSelector found", "spec.placement.clusterSelector", fdeploy.Spec.Placement.ClusterSelector)
-		sel, err := metav1.LabelSelectorAsSelector(fdeploy.Spec.Placement.ClusterSelector)
-		if err != nil {
-			lg.Error(err, "placement.clusterSelector")
-			return nil, err
-		}
-		cl := &fedcorev1b1.KubeFedClusterList{}
-		if err := r.List(ctx, cl, &client.ListOptions{
-			Namespace:     wfc.Spec.KubeFedNamespace,
-			LabelSelector: sel,
-		}); err != nil {
-			return nil, err
-		}
-		for _, c := range cl.Items {
-			candidates = append(candidates, c.Name)
-		}
+		return &rspOptimizeResult{clusters: map[string]fedschedv1a1.ClusterPreferences{}}, *wfc.Spec.Scheduling.Optimizer.Method, nil
 	}
 
 	// filter candidates
-	// remove invalid or duplicated clusters
-	// NOTE: FederatedDeployment spec.placement does not guarantee its validity
-	var clusters []string
-
-	// NOTE: all KubeFedClusters are considered valid, only unregistered clusters will be removed
-	cl := &fedcorev1b1.KubeFedClusterList{}
-	if err := r.List(ctx, cl, &client.ListOptions{
-		Namespace: wfc.Spec.KubeFedNamespace,
-	}); err != nil {
-		return nil, err
-	}
-	registered := map[string]struct{}{}
-	for _, c := range cl.Items {
-		registered[c.Name] = struct{}{}
+	// remove unregistered, unhealthy (not Ready or Offline) or duplicated clusters
+	clusters, excluded, err := listCandidateClusters(ctx, r.Client, placement, wfc.Spec.KubeFedNamespace)
+	if err != nil {
+		return nil, "", err
 	}
 
-	dedup := map[string]int{}
+	lg.Info("schedulable clusters", "clusters", clusters)
 
-	for _, c := range candidates {
-		// check registration
-		if _, ok := registered[c]; !ok {
-			continue
-		}
-		// deduplication
-		dedup[c] += 1
-		if dedup[c] == 1 {
-			clusters = append(clusters, c)
+	// discover WAO-Estimators of clusters not listed in waoEstimators
+	settings := wfc.Spec.Scheduling.Optimizer
+	if d := settings.WAOEstimatorDiscovery; d != nil && rspOptimizerUsesWAOEstimators(settings) {
+		estimators, err := listWAOEstimators(ctx, r.Client, wfc.Spec.KubeFedNamespace, settings.WAOEstimators, d)
+		if err != nil {
+			return nil, "", err
 		}
+		settings = settings.DeepCopy()
+		settings.WAOEstimators = estimators
 	}
 
-	lg.Info("schedulable clusters", "clusters", clusters)
-
 	// optimize cluster weights
-	optimizeFn, ok := rspOptimizeFuncCollection[*wfc.Spec.Scheduling.Optimizer.Method]
-	if !ok {
-		return nil, fmt.Errorf("invalid method \"%v\"", wfc.Spec.Scheduling.Optimizer.Method)
+	// try the method and the fallback methods in order until one succeeds
+	var errs []error
+	for _, method := range settings.Methods() {
+		optimizeFn, ok := rspOptimizeFuncCollection[method]
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid method \"%v\"", method))
+			continue
+		}
+		res, err := optimizeFn(ctx, r.Client, wfc.Spec.KubeFedNamespace, clusters, settings, fdeploy)
+		if err != nil {
+			lg.Error(err, "method failed, try the next fallback method if any", "method", method)
+			errs = append(errs, fmt.Errorf("method %s: %w", method, err))
+			continue
+		}
+		lg.Info("optimize weights", "method", method, "weights", res.clusters)
+		res.excluded = excluded
+		return res, method, nil
 	}
-	cps, err := optimizeFn(ctx, clusters, wfc.Spec.Scheduling.Optimizer, fdeploy)
-	if err != nil {
-		return nil, err
+	if len(errs) == 0 {
+		return nil, "", fmt.Errorf("no method specified")
 	}
-	lg.Info("optimize weights", "weights", cps)
-	return cps, nil
+	return nil, "", utilerrors.NewAggregate(errs)
 }
 
-type rspOptimizeFunc func(ctx context.Context, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (map[string]fedschedv1a1.ClusterPreferences, error)
+type rspOptimizeFunc func(ctx context.Context, c client.Reader, kubefedNamespace string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error)
+
+// rspOptimizeResult is the result of a rspOptimizeFunc.
+type rspOptimizeResult struct {
+	// clusters is the cluster preferences to set in the RSP.
+	clusters map[string]fedschedv1a1.ClusterPreferences
+	// cost estimates the cost of an allocation with the costs used by the method, and is nil if the method does not estimate costs.
+	// Ref. newPatternCostFunc
+	cost func(weights map[string]int64) (float64, bool)
+	// excluded is the clusters excluded from the candidates with the reasons, which is set by optimizeClusterWeights.
+	// Ref. listCandidateClusters
+	excluded map[string]string
+}
 
 var rspOptimizeFuncCollection = map[v1beta1.RSPOptimizerMethod]rspOptimizeFunc{
 	v1beta1.RSPOptimizerMethodRoundRobin: rspOptimizeFnRoundRobin,
 	v1beta1.RSPOptimizerMethodWAO:        rspOptimizeFnWAO,
+	v1beta1.RSPOptimizerMethodCapacity:   rspOptimizeFnCapacity,
+	v1beta1.RSPOptimizerMethodCarbon:     rspOptimizeFnCarbon,
+	v1beta1.RSPOptimizerMethodPrice:      rspOptimizeFnPrice,
+	v1beta1.RSPOptimizerMethodWebhook:    rspOptimizeFnWebhook,
+}
+
+// rspOptimizerUsesWAOEstimators reports whether the method or the fallback methods of the settings calls WAO-Estimators.
+func rspOptimizerUsesWAOEstimators(settings *v1beta1.RSPOptimizerSettings) bool {
+	if settings == nil {
+		return false
+	}
+	for _, m := range settings.Methods() {
+		switch m {
+		case v1beta1.RSPOptimizerMethodWAO, v1beta1.RSPOptimizerMethodPrice:
+			return true
+		case v1beta1.RSPOptimizerMethodCarbon:
+			if settings.CarbonIntensity != nil && settings.CarbonIntensity.UseWAOEstimators {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func rspOptimizeFnRoundRobin(_ context.Context, clusters []string, _ *v1beta1.RSPOptimizerSettings, _ *structuredFederatedDeployment) (map[string]fedschedv1a1.ClusterPreferences, error) {
+// rspReoptimizeAfter returns the duration until the next re-optimization, or 0 if not required.
+// It is spec.scheduling.optimizer.reoptimizeInterval, or the next tariff band change if it comes earlier
+// and method, the method that computed the current RSP (possibly a fallback method), is "price".
+func rspReoptimizeAfter(settings *v1beta1.RSPOptimizerSettings, method v1beta1.RSPOptimizerMethod, now time.Time) time.Duration {
+	var d time.Duration
+	if settings.ReoptimizeInterval != nil && settings.ReoptimizeInterval.Duration > 0 {
+		d = settings.ReoptimizeInterval.Duration
+	}
+	if method == v1beta1.RSPOptimizerMethodPrice {
+		for _, tariff := range settings.Tariffs {
+			next, err := tariff.NextChange(now)
+			if err != nil {
+				continue
+			}
+			if untilNext := next.Sub(now); d == 0 || untilNext < d {
+				d = untilNext
+			}
+		}
+	}
+	return d
+}
+
+// clusterReplicaBounds returns the replica bounds for the cluster.
+// An explicit mapping takes precedence over "*", and clusters without bounds are unbounded.
+func clusterReplicaBounds(settings *v1beta1.RSPOptimizerSettings, cluster string) v1beta1.ReplicaBounds {
+	if settings == nil {
+		return v1beta1.ReplicaBounds{}
+	}
+	if b, ok := settings.ReplicaBounds[cluster]; ok {
+		return b
+	}
+	return settings.ReplicaBounds["*"]
+}
+
+// placementWithDefault returns the placement if it has clusters or clusterSelector,
+// otherwise the placement specified by defaultPlacement, or nil if no clusters should be candidates.
+func placementWithDefault(placement *fedctrlutil.GenericPlacementFields, dp *v1beta1.DefaultPlacement) *fedctrlutil.GenericPlacementFields {
+	if placement != nil && (placement.Clusters != nil || placement.ClusterSelector != nil) {
+		return placement
+	}
+	if dp == nil {
+		return nil
+	}
+	switch dp.Type {
+	case v1beta1.DefaultPlacementAllClusters:
+		// an empty selector selects all clusters
+		return &fedctrlutil.GenericPlacementFields{ClusterSelector: &metav1.LabelSelector{}}
+	case v1beta1.DefaultPlacementClusterSelector:
+		if dp.ClusterSelector == nil {
+			return nil
+		}
+		return &fedctrlutil.GenericPlacementFields{ClusterSelector: dp.ClusterSelector}
+	default:
+		return nil
+	}
+}
+
+func rspOptimizeFnRoundRobin(_ context.Context, _ client.Reader, _ string, clusters []string, settings *v1beta1.RSPOptimizerSettings, _ *structuredFederatedDeployment) (*rspOptimizeResult, error) {
 	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(clusters))
 	for _, cl := range clusters {
+		b := clusterReplicaBounds(settings, cl)
 		cps[cl] = fedschedv1a1.ClusterPreferences{
-			MinReplicas: 0,
-			MaxReplicas: nil,
+			MinReplicas: b.MinReplicas,
+			MaxReplicas: b.MaxReplicas,
 			Weight:      1,
 		}
 	}
-	return cps, nil
+	return &rspOptimizeResult{clusters: cps}, nil
 }
 
-func rspOptimizeFnWAO(ctx context.Context, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (map[string]fedschedv1a1.ClusterPreferences, error) {
+func rspOptimizeFnWAO(ctx context.Context, c client.Reader, _ string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error) {
 	lg := log.FromContext(ctx)
 	lg.Info("rspOptimizeFnWAO")
 
@@ -314,18 +649,48 @@ func rspOptimizeFnWAO(ctx context.Context, clusters []string, settings *v1beta1.
 		return nil, fmt.Errorf("wrong fdeploy: fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil")
 	}
 
-	totalCPUMilli := 0
-	for _, c := range fdeploy.Spec.Template.Spec.Template.Spec.Containers {
-		totalCPUMilli += int(c.Resources.Requests.Cpu().MilliValue())
+	cpuMilli, replicas := waoWorkloads(ctx, []*structuredFederatedDeployment{fdeploy})
+
+	bounds := make([]v1beta1.ReplicaBounds, len(clusters))
+	for i, cl := range clusters {
+		bounds[i] = clusterReplicaBounds(settings, cl)
+	}
+
+	costs := estimatePowerIncreasesWAO(ctx, c, clusters, settings.WAOEstimators, cpuMilli, replicas)
+	lg.Info("call ComputeLeastCostPatternsFn", "clusters", clusters, "costs", costs, "bounds", bounds)
+	minCost, pattern, err := computeLeastCostPatternWithBounds(costs, replicas, bounds)
+	if err != nil {
+		return nil, err
 	}
+	lg.Info("called ComputeLeastCostPatternsFn", "minCost", minCost, "clusters", clusters, "pattern", pattern)
 
-	replicas := 0
-	if fdeploy.Spec.Template.Spec.Replicas != nil {
-		replicas = int(*(fdeploy.Spec.Template.Spec.Replicas))
+	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(clusters))
+	for i, cl := range clusters {
+		cps[cl] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: bounds[i].MinReplicas,
+			MaxReplicas: bounds[i].MaxReplicas,
+			Weight:      int64(pattern[i]),
+		}
 	}
 
-	estimatedCosts := make([][]float64, len(clusters))
+	return &rspOptimizeResult{clusters: cps, cost: newPatternCostFunc(clusters, costs)}, nil
+}
+
+// rspOptimizeFnCapacity weights clusters by the number of pods of the template that can be scheduled on them,
+// which is computed from the allocatable resources of the nodes minus the requests of the running pods in each member cluster.
+// It fails if the capacity cannot be counted on any cluster, so that the fallback methods are tried,
+// and the measured clusters fall back to the same weight if no pods can be scheduled on any of them.
+func rspOptimizeFnCapacity(ctx context.Context, c client.Reader, kubefedNamespace string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("rspOptimizeFnCapacity")
 
+	if fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fdeploy: fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil")
+	}
+	requests := podResources(&fdeploy.Spec.Template.Spec.Template.Spec, false)
+
+	weights := make([]int64, len(clusters))
+	measured := make([]bool, len(clusters))
 	var wg sync.WaitGroup
 	for i, cluster := range clusters {
 		i := i
@@ -333,50 +698,178 @@ func rspOptimizeFnWAO(ctx context.Context, clusters []string, settings *v1beta1.
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-
-			costs := make([]float64, replicas)
-			for i := range costs {
-				costs[i] = math.Inf(1)
-			}
-
-			conf := settings.WAOEstimators[cluster]
-			var reqBuf bytes.Buffer
-			c, err := estimator.NewClient(conf.Endpoint, conf.Namespace, conf.Name, estimator.ClientOptionGetRequestAsCurl(&reqBuf))
+			n, err := countSchedulablePodsInCluster(ctx, c, kubefedNamespace, cluster, requests)
 			if err != nil {
-				lg.Error(err, "estimator.NewClient", "cluster", cluster)
+				lg.Error(err, "unable to count schedulable pods", "cluster", cluster)
+				return
 			}
-			pc, apiErr, err := c.EstimatePowerConsumption(ctx, totalCPUMilli, replicas)
-			lg.Info("call EstimatePowerConsumption", "cluster", cluster, "request", reqBuf.String())
-			if err != nil {
-				lg.Error(err, "EstimatePowerConsumption", "cluster", cluster)
-			} else if apiErr != nil {
-				lg.Error(fmt.Errorf("%v (%w)", apiErr.Message, estimator.GetErrorFromCode(*apiErr)), "EstimatePowerConsumption", "cluster", cluster)
-			} else {
-				costs = *pc.WattIncreases
-			}
-			estimatedCosts[i] = costs
+			weights[i] = n
+			measured[i] = true
 		}()
 	}
 	wg.Wait()
+	lg.Info("schedulable pods", "clusters", clusters, "requests", requests, "weights", weights, "measured", measured)
+
+	var sum int64
+	var nMeasured int
+	for i, w := range weights {
+		sum += w
+		if measured[i] {
+			nMeasured++
+		}
+	}
+	if len(clusters) > 0 && nMeasured == 0 {
+		return nil, fmt.Errorf("unable to count schedulable pods on any cluster")
+	}
 
-	lg.Info("call ComputeLeastCostPatternsFn", "clusters", clusters, "costs", estimatedCosts)
+	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(clusters))
+	for i, cl := range clusters {
+		w := weights[i]
+		if sum == 0 && measured[i] {
+			w = 1
+		}
+		b := clusterReplicaBounds(settings, cl)
+		cps[cl] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: b.MinReplicas,
+			MaxReplicas: b.MaxReplicas,
+			Weight:      w,
+		}
+	}
+	return &rspOptimizeResult{clusters: cps}, nil
+}
+
+// rspOptimizeFnCarbon weights clusters by the grid carbon intensity.
+// With carbonIntensity.useWAOEstimators, clusters get the number of pods that minimizes the total emissions,
+// that is the sum of the power increases estimated by WAO-Estimators multiplied by the carbon intensities.
+// Otherwise, clusters are weighted by the inverse of the carbon intensities.
+func rspOptimizeFnCarbon(ctx context.Context, c client.Reader, _ string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("rspOptimizeFnCarbon")
+
+	if fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fdeploy: fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil")
+	}
+	if settings.CarbonIntensity == nil {
+		return nil, fmt.Errorf("carbonIntensity is not specified")
+	}
+
+	intensities, err := getCarbonIntensities(ctx, c, settings.CarbonIntensity, clusters)
+	if err != nil {
+		return nil, err
+	}
+	lg.Info("carbon intensities", "clusters", clusters, "intensities", intensities)
+
+	bounds := make([]v1beta1.ReplicaBounds, len(clusters))
+	for i, cl := range clusters {
+		bounds[i] = clusterReplicaBounds(settings, cl)
+	}
+
+	var weights []int64
+	var cost func(map[string]int64) (float64, bool)
+	if settings.CarbonIntensity.UseWAOEstimators {
+		cpuMilli, replicas := waoWorkloads(ctx, []*structuredFederatedDeployment{fdeploy})
+		costs := scaleCosts(estimatePowerIncreasesWAO(ctx, c, clusters, settings.WAOEstimators, cpuMilli, replicas), intensities)
+		minCost, pattern, err := computeLeastCostPatternWithBounds(costs, replicas, bounds)
+		if err != nil {
+			return nil, err
+		}
+		lg.Info("least emission pattern", "minCost", minCost, "clusters", clusters, "pattern", pattern)
+		weights = make([]int64, len(pattern))
+		for i := range pattern {
+			weights[i] = int64(pattern[i])
+		}
+		cost = newPatternCostFunc(clusters, costs)
+	} else {
+		weights = carbonWeights(intensities)
+	}
+
+	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(clusters))
+	for i, cl := range clusters {
+		cps[cl] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: bounds[i].MinReplicas,
+			MaxReplicas: bounds[i].MaxReplicas,
+			Weight:      weights[i],
+		}
+	}
+	return &rspOptimizeResult{clusters: cps, cost: cost}, nil
+}
+
+// rspOptimizeFnPrice gives clusters the number of pods that minimizes the total electricity cost,
+// that is the sum of the power increases estimated by WAO-Estimators multiplied by the current electricity prices.
+func rspOptimizeFnPrice(ctx context.Context, c client.Reader, _ string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("rspOptimizeFnPrice")
+
+	if fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fdeploy: fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil")
+	}
 
-	minCost, minCostPatterns, err := estimator.ComputeLeastCostPatternsFn(len(clusters), replicas, estimatedCosts)
+	prices, err := currentElectricityPrices(settings.Tariffs, clusters, time.Now())
 	if err != nil {
 		return nil, err
 	}
+	lg.Info("electricity prices", "clusters", clusters, "prices", prices)
 
-	lg.Info("called ComputeLeastCostPatternsFn", "minCost", minCost, "clusters", clusters, "minCostPatterns", minCostPatterns)
+	bounds := make([]v1beta1.ReplicaBounds, len(clusters))
+	for i, cl := range clusters {
+		bounds[i] = clusterReplicaBounds(settings, cl)
+	}
 
-	// NOTE: use the first pattern at this time
-	weights := minCostPatterns[0]
+	cpuMilli, replicas := waoWorkloads(ctx, []*structuredFederatedDeployment{fdeploy})
+	costs := scaleCosts(estimatePowerIncreasesWAO(ctx, c, clusters, settings.WAOEstimators, cpuMilli, replicas), prices)
+	minCost, pattern, err := computeLeastCostPatternWithBounds(costs, replicas, bounds)
+	if err != nil {
+		return nil, err
+	}
+	lg.Info("least cost pattern", "minCost", minCost, "clusters", clusters, "pattern", pattern)
 
 	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(clusters))
-	for i, c := range clusters {
-		cps[c] = fedschedv1a1.ClusterPreferences{
-			Weight: int64(weights[i]),
+	for i, cl := range clusters {
+		cps[cl] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: bounds[i].MinReplicas,
+			MaxReplicas: bounds[i].MaxReplicas,
+			Weight:      int64(pattern[i]),
 		}
 	}
+	return &rspOptimizeResult{clusters: cps, cost: newPatternCostFunc(clusters, costs)}, nil
+}
+
+// rspOptimizeFnWebhook delegates the optimization to the external optimizer specified by spec.scheduling.optimizer.webhook.
+// The min/max replicas returned by the external optimizer are intersected with spec.scheduling.optimizer.replicaBounds,
+// and clusters not returned get no replicas.
+func rspOptimizeFnWebhook(ctx context.Context, _ client.Reader, _ string, clusters []string, settings *v1beta1.RSPOptimizerSettings, fdeploy *structuredFederatedDeployment) (*rspOptimizeResult, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("rspOptimizeFnWebhook")
 
-	return cps, nil
+	if fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fdeploy: fdeploy == nil || fdeploy.Spec == nil || fdeploy.Spec.Template == nil")
+	}
+	if settings.Webhook == nil {
+		return nil, fmt.Errorf("webhook is not specified")
+	}
+
+	req, err := newOptimizeRequest(v1beta1.OptimizeTypeScheduling, clusters, fdeploy, fdeploy.templateReplicas(), podResources(&fdeploy.Spec.Template.Spec.Template.Spec, false))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := callOptimizerWebhook(ctx, settings.Webhook, req)
+	if err != nil {
+		return nil, err
+	}
+	lg.Info("webhook response", "clusters", resp.Clusters)
+
+	cps := make(map[string]fedschedv1a1.ClusterPreferences, len(resp.Clusters))
+	for _, cl := range clusters {
+		p, ok := resp.Clusters[cl]
+		if !ok {
+			continue
+		}
+		b := intersectReplicaBounds(clusterReplicaBounds(settings, cl), v1beta1.ReplicaBounds{MinReplicas: p.MinReplicas, MaxReplicas: p.MaxReplicas})
+		cps[cl] = fedschedv1a1.ClusterPreferences{
+			MinReplicas: b.MinReplicas,
+			MaxReplicas: b.MaxReplicas,
+			Weight:      p.Weight,
+		}
+	}
+	return &rspOptimizeResult{clusters: cps}, nil
 }
@@ -3,14 +3,27 @@ package controllers
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
 	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
 )
 
 func Test_optimizeFnRoundRobin(t *testing.T) {
 	type args struct {
 		clusters []string
+		settings *v1beta1.RSPOptimizerSettings
 	}
 	tests := []struct {
 		name    string
@@ -18,15 +31,15 @@ func Test_optimizeFnRoundRobin(t *testing.T) {
 		want    map[string]fedschedv1a1.ClusterPreferences
 		wantErr bool
 	}{
-		{"empty", args{[]string{}}, map[string]fedschedv1a1.ClusterPreferences{}, false},
-		{"1cluster", args{[]string{"c1"}}, map[string]fedschedv1a1.ClusterPreferences{
+		{"empty", args{[]string{}, nil}, map[string]fedschedv1a1.ClusterPreferences{}, false},
+		{"1cluster", args{[]string{"c1"}, nil}, map[string]fedschedv1a1.ClusterPreferences{
 			"c1": {
 				MinReplicas: 0,
 				MaxReplicas: nil,
 				Weight:      1,
 			},
 		}, false},
-		{"3clusters", args{[]string{"c1", "c2", "c3"}}, map[string]fedschedv1a1.ClusterPreferences{
+		{"3clusters", args{[]string{"c1", "c2", "c3"}, nil}, map[string]fedschedv1a1.ClusterPreferences{
 			"c1": {
 				MinReplicas: 0,
 				MaxReplicas: nil,
@@ -43,17 +56,241 @@ func Test_optimizeFnRoundRobin(t *testing.T) {
 				Weight:      1,
 			},
 		}, false},
+		{"bounds", args{[]string{"c1", "c2", "c3"}, &v1beta1.RSPOptimizerSettings{
+			ReplicaBounds: map[string]v1beta1.ReplicaBounds{
+				"*":  {MinReplicas: 1},
+				"c2": {MaxReplicas: pointer.Int64(2)},
+			},
+		}}, map[string]fedschedv1a1.ClusterPreferences{
+			"c1": {
+				MinReplicas: 1,
+				MaxReplicas: nil,
+				Weight:      1,
+			},
+			"c2": {
+				MinReplicas: 0,
+				MaxReplicas: pointer.Int64(2),
+				Weight:      1,
+			},
+			"c3": {
+				MinReplicas: 1,
+				MaxReplicas: nil,
+				Weight:      1,
+			},
+		}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := rspOptimizeFnRoundRobin(context.Background(), tt.args.clusters, nil, nil)
+			res, err := rspOptimizeFnRoundRobin(context.Background(), nil, "", tt.args.clusters, tt.args.settings, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("optimizeFnRoundRobin() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			var got map[string]fedschedv1a1.ClusterPreferences
+			if res != nil {
+				got = res.clusters
+			}
 			if cmp.Diff(got, tt.want) != "" {
 				t.Errorf("optimizeFnRoundRobin() = %v, want %v, diff %s", got, tt.want, cmp.Diff(got, tt.want))
 			}
 		})
 	}
 }
+
+func Test_RSPOptimizerReconciler_optimizeClusterWeights_fallback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = fedcorev1b1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		helperReadyKubeFedCluster("c1", nil),
+		helperReadyKubeFedCluster("c2", nil),
+	).Build()
+	r := &RSPOptimizerReconciler{Client: c, Scheme: scheme}
+
+	fdeploy := &structuredFederatedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "fdeploy"},
+		Spec: &structuredFederatedDeploymentSpec{
+			Template: &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(2)}},
+			Placement: &util.GenericPlacementFields{
+				Clusters: []util.GenericClusterReference{{Name: "c1"}, {Name: "c2"}},
+			},
+		},
+	}
+	method := func(m v1beta1.RSPOptimizerMethod) *v1beta1.RSPOptimizerMethod { return &m }
+	// the webhook always fails as nothing listens on the port
+	webhook := &v1beta1.OptimizerWebhook{URL: "http://127.0.0.1:1", Timeout: &metav1.Duration{Duration: time.Second}}
+	rr := map[string]fedschedv1a1.ClusterPreferences{"c1": {Weight: 1}, "c2": {Weight: 1}}
+
+	tests := []struct {
+		name       string
+		settings   *v1beta1.RSPOptimizerSettings
+		want       map[string]fedschedv1a1.ClusterPreferences
+		wantMethod v1beta1.RSPOptimizerMethod
+		wantErr    bool
+	}{
+		{"primary", &v1beta1.RSPOptimizerSettings{
+			Method: method(v1beta1.RSPOptimizerMethodRoundRobin),
+		}, rr, v1beta1.RSPOptimizerMethodRoundRobin, false},
+		{"fallback", &v1beta1.RSPOptimizerSettings{
+			Method: method(v1beta1.RSPOptimizerMethodWebhook), Webhook: webhook,
+			FallbackMethods: []v1beta1.RSPOptimizerMethod{v1beta1.RSPOptimizerMethodWebhook, v1beta1.RSPOptimizerMethodCarbon, v1beta1.RSPOptimizerMethodRoundRobin},
+		}, rr, v1beta1.RSPOptimizerMethodRoundRobin, false},
+		{"all_failed", &v1beta1.RSPOptimizerSettings{
+			Method: method(v1beta1.RSPOptimizerMethodWebhook), Webhook: webhook,
+			FallbackMethods: []v1beta1.RSPOptimizerMethod{v1beta1.RSPOptimizerMethodCarbon},
+		}, nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wfc := &v1beta1.WAOFedConfig{Spec: v1beta1.WAOFedConfigSpec{
+				KubeFedNamespace: "kube-federation-system",
+				Scheduling:       &v1beta1.SchedulingSettings{Optimizer: tt.settings},
+			}}
+			res, gotMethod, err := r.optimizeClusterWeights(context.Background(), fdeploy, wfc)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("optimizeClusterWeights() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got map[string]fedschedv1a1.ClusterPreferences
+			if res != nil {
+				got = res.clusters
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("optimizeClusterWeights() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("optimizeClusterWeights() method = %v, want %v", gotMethod, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func Test_RSPOptimizerReconciler_optimizeClusterWeights_defaultPlacement(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = fedcorev1b1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		helperReadyKubeFedCluster("c1", map[string]string{"region": "tokyo"}),
+		helperReadyKubeFedCluster("c2", nil),
+	).Build()
+	r := &RSPOptimizerReconciler{Client: c, Scheme: scheme}
+
+	rr := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodRoundRobin)
+	tokyo := &metav1.LabelSelector{MatchLabels: map[string]string{"region": "tokyo"}}
+
+	tests := []struct {
+		name      string
+		placement *util.GenericPlacementFields
+		dp        *v1beta1.DefaultPlacement
+		want      map[string]fedschedv1a1.ClusterPreferences
+	}{
+		{"no_default", nil, nil, map[string]fedschedv1a1.ClusterPreferences{}},
+		{"none", nil, &v1beta1.DefaultPlacement{Type: v1beta1.DefaultPlacementNone}, map[string]fedschedv1a1.ClusterPreferences{}},
+		{"all_clusters", nil, &v1beta1.DefaultPlacement{Type: v1beta1.DefaultPlacementAllClusters},
+			map[string]fedschedv1a1.ClusterPreferences{"c1": {Weight: 1}, "c2": {Weight: 1}}},
+		{"cluster_selector", &util.GenericPlacementFields{}, &v1beta1.DefaultPlacement{Type: v1beta1.DefaultPlacementClusterSelector, ClusterSelector: tokyo},
+			map[string]fedschedv1a1.ClusterPreferences{"c1": {Weight: 1}}},
+		{"placement_preferred", &util.GenericPlacementFields{Clusters: []util.GenericClusterReference{{Name: "c2"}}}, &v1beta1.DefaultPlacement{Type: v1beta1.DefaultPlacementAllClusters},
+			map[string]fedschedv1a1.ClusterPreferences{"c2": {Weight: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fdeploy := &structuredFederatedDeployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "fdeploy"},
+				Spec: &structuredFederatedDeploymentSpec{
+					Template:  &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(2)}},
+					Placement: tt.placement,
+				},
+			}
+			wfc := &v1beta1.WAOFedConfig{Spec: v1beta1.WAOFedConfigSpec{
+				KubeFedNamespace: "kube-federation-system",
+				Scheduling:       &v1beta1.SchedulingSettings{Optimizer: &v1beta1.RSPOptimizerSettings{Method: &rr, DefaultPlacement: tt.dp}},
+			}}
+			res, _, err := r.optimizeClusterWeights(context.Background(), fdeploy, wfc)
+			if err != nil {
+				t.Errorf("optimizeClusterWeights() error = %v", err)
+				return
+			}
+			if diff := cmp.Diff(res.clusters, tt.want); diff != "" {
+				t.Errorf("optimizeClusterWeights() = %v, want %v, diff %s", res.clusters, tt.want, diff)
+			}
+		})
+	}
+}
+
+func Test_RSPOptimizerReconciler_reconcileRSP_template(t *testing.T) {
+	rr := v1beta1.RSPOptimizerMethod(v1beta1.RSPOptimizerMethodRoundRobin)
+	wfc := &v1beta1.WAOFedConfig{Spec: v1beta1.WAOFedConfigSpec{
+		KubeFedNamespace: "kube-federation-system",
+		Scheduling:       &v1beta1.SchedulingSettings{Optimizer: &v1beta1.RSPOptimizerSettings{Method: &rr}},
+	}}
+	placement := &util.GenericPlacementFields{Clusters: []util.GenericClusterReference{{Name: "c1"}, {Name: "c2"}}}
+
+	tests := []struct {
+		name              string
+		template          *appsv1.Deployment
+		wantTotalReplicas *int32 // nil if no RSP is created
+		wantMethod        v1beta1.RSPOptimizerMethod
+	}{
+		{"replicas", &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: pointer.Int32(3)}}, pointer.Int32(3), rr},
+		{"no_replicas", &appsv1.Deployment{}, pointer.Int32(1), rr},
+		{"no_template", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = fedcorev1b1.AddToScheme(scheme)
+			_ = fedschedv1a1.AddToScheme(scheme)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				helperReadyKubeFedCluster("c1", nil),
+				helperReadyKubeFedCluster("c2", nil),
+			).Build()
+			r := &RSPOptimizerReconciler{Client: c, Scheme: scheme}
+			fdeploy := &structuredFederatedDeployment{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "types.kubefed.io/v1beta1", Kind: "FederatedDeployment"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "fdeploy"},
+				Spec:       &structuredFederatedDeploymentSpec{Template: tt.template, Placement: placement},
+			}
+
+			_, gotMethod, err := r.reconcileRSP(context.Background(), fdeploy, wfc.DeepCopy(), true)
+			if err != nil {
+				t.Fatalf("reconcileRSP() error = %v", err)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("reconcileRSP() method = %v, want %v", gotMethod, tt.wantMethod)
+			}
+			rsp := &fedschedv1a1.ReplicaSchedulingPreference{}
+			err = c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "fdeploy"}, rsp)
+			if tt.wantTotalReplicas == nil {
+				if !errors.IsNotFound(err) {
+					t.Errorf("RSP error = %v, want NotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to get RSP: %v", err)
+			}
+			if rsp.Spec.TotalReplicas != *tt.wantTotalReplicas {
+				t.Errorf("RSP spec.totalReplicas = %v, want %v", rsp.Spec.TotalReplicas, *tt.wantTotalReplicas)
+			}
+		})
+	}
+}
+
+func Test_rspOptimizeFnWAO_nilReplicas(t *testing.T) {
+	srv, _ := helperWAOEstimatorServer(t, 0, nil)
+	conf := &v1beta1.WAOEstimatorSetting{Endpoint: srv.URL, Namespace: "default", Name: "default"}
+	settings := &v1beta1.RSPOptimizerSettings{WAOEstimators: map[string]*v1beta1.WAOEstimatorSetting{"c1": conf, "c2": conf}}
+	// spec.template.spec.replicas is defaulted to 1 by the API server
+	fdeploy := helperFederatedDeploymentWithCPU(nil, "100m")
+
+	res, err := rspOptimizeFnWAO(context.Background(), nil, "", []string{"c1", "c2"}, settings, fdeploy)
+	if err != nil {
+		t.Fatalf("rspOptimizeFnWAO() error = %v", err)
+	}
+	var sum int64
+	for _, cp := range res.clusters {
+		sum += cp.Weight
+	}
+	if sum != 1 {
+		t.Errorf("rspOptimizeFnWAO() = %v, want weights for 1 replica", res.clusters)
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// matchResourceSelector reports whether the federated object is selected by the ResourceSelector.
+// The namespace of the federated object is fetched only if sel.NamespaceSelector is specified.
+func matchResourceSelector(ctx context.Context, c client.Reader, sel *v1beta1.ResourceSelector, obj metav1.Object) (bool, error) {
+	var nsLabels labels.Set
+	if sel.NamespaceSelector != nil && !isExcludedNamespace(sel, obj.GetNamespace()) {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, ns); err != nil {
+			return false, err
+		}
+		nsLabels = ns.Labels
+	}
+	return matchResourceSelectorWithNamespaceLabels(sel, obj, nsLabels)
+}
+
+// matchResourceSelectorWithNamespaceLabels is the same as matchResourceSelector
+// but uses the given labels as the labels of the namespace.
+//
+// NOTE: the defaulting webhook ensures sel.Any != nil && sel.HasAnnotation != nil
+func matchResourceSelectorWithNamespaceLabels(sel *v1beta1.ResourceSelector, obj metav1.Object, nsLabels labels.Set) (bool, error) {
+	// check namespace
+	if isExcludedNamespace(sel, obj.GetNamespace()) {
+		return false, nil
+	}
+	if sel.NamespaceSelector != nil {
+		nsSel, err := metav1.LabelSelectorAsSelector(sel.NamespaceSelector)
+		if err != nil {
+			return false, err
+		}
+		if !nsSel.Matches(nsLabels) {
+			return false, nil
+		}
+	}
+
+	// check selector.any
+	if *sel.Any {
+		return true, nil
+	}
+	// check the annotation exists in the federated object
+	// currently the value is ignored
+	if _, ok := obj.GetAnnotations()[*sel.HasAnnotation]; ok {
+		return true, nil
+	}
+	// check the labels of the federated object
+	if sel.ObjectSelector != nil {
+		objSel, err := metav1.LabelSelectorAsSelector(sel.ObjectSelector)
+		if err != nil {
+			return false, err
+		}
+		return objSel.Matches(labels.Set(obj.GetLabels())), nil
+	}
+	return false, nil
+}
+
+func isExcludedNamespace(sel *v1beta1.ResourceSelector, ns string) bool {
+	for _, v := range sel.ExcludeNamespaces {
+		if v == ns {
+			return true
+		}
+	}
+	return false
+}
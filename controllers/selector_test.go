@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/pointer"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_matchResourceSelectorWithNamespaceLabels(t *testing.T) {
+	const annotation = "waofed.bitmedia.co.jp/scheduling"
+	helperSelector := func(any bool, objSel, nsSel *metav1.LabelSelector, exclude ...string) *v1beta1.ResourceSelector {
+		return &v1beta1.ResourceSelector{
+			Any:               pointer.Bool(any),
+			HasAnnotation:     pointer.String(annotation),
+			ObjectSelector:    objSel,
+			NamespaceSelector: nsSel,
+			ExcludeNamespaces: exclude,
+		}
+	}
+	helperObj := func(annotations, labels map[string]string) *metav1.ObjectMeta {
+		return &metav1.ObjectMeta{Name: "foo", Namespace: "ns1", Annotations: annotations, Labels: labels}
+	}
+	appSel := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}
+	envSel := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	invalidSel := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Invalid"}}}
+	prod := labels.Set{"env": "prod"}
+
+	tests := []struct {
+		name     string
+		sel      *v1beta1.ResourceSelector
+		obj      *metav1.ObjectMeta
+		nsLabels labels.Set
+		want     bool
+		wantErr  bool
+	}{
+		{"none", helperSelector(false, nil, nil), helperObj(nil, nil), nil, false, false},
+		{"any", helperSelector(true, nil, nil), helperObj(nil, nil), nil, true, false},
+		{"annotation", helperSelector(false, nil, nil), helperObj(map[string]string{annotation: ""}, nil), nil, true, false},
+		{"object_selector", helperSelector(false, appSel, nil), helperObj(nil, map[string]string{"app": "foo"}), nil, true, false},
+		{"object_selector_mismatch", helperSelector(false, appSel, nil), helperObj(nil, map[string]string{"app": "bar"}), nil, false, false},
+		{"namespace_selector", helperSelector(true, nil, envSel), helperObj(nil, nil), prod, true, false},
+		{"namespace_selector_mismatch", helperSelector(true, nil, envSel), helperObj(nil, nil), labels.Set{"env": "dev"}, false, false},
+		{"namespace_selector_only", helperSelector(false, nil, envSel), helperObj(nil, nil), prod, false, false},
+		{"excluded", helperSelector(true, nil, nil, "kube-system", "ns1"), helperObj(nil, nil), nil, false, false},
+		{"excluded_namespace_selector", helperSelector(true, nil, envSel, "ns1"), helperObj(nil, nil), prod, false, false},
+		{"invalid_object_selector", helperSelector(false, invalidSel, nil), helperObj(nil, nil), nil, false, true},
+		{"invalid_namespace_selector", helperSelector(true, nil, invalidSel), helperObj(nil, nil), nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchResourceSelectorWithNamespaceLabels(tt.sel, tt.obj, tt.nsLabels)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("matchResourceSelectorWithNamespaceLabels() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("matchResourceSelectorWithNamespaceLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
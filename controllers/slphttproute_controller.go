@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// SLPHTTPRouteReconciler reconciles a ServiceLoadbalancingPreference object
+// by applying its weights to Gateway API HTTPRoutes.
+type SLPHTTPRouteReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	ControllerName string
+}
+
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=serviceloadbalancingpreferences,verbs=get;list;watch
+//+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=serviceloadbalancingpreferences/status,verbs=get;update;patch
+
+// SetupWithManager sets up the controller with the Manager.
+//
+// The controller is skipped if Gateway API HTTPRoute is not installed.
+func (r *SLPHTTPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.ControllerName = v1beta1.OperatorName + "-slphttproute-controller"
+
+	lg := mgr.GetLogger().WithName(r.ControllerName)
+
+	if _, err := mgr.GetRESTMapper().RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			lg.Info("skip as the kind is not installed", "gvk", httpRouteGVK)
+			return nil
+		}
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// status updates (by SLPOptimizer or this controller) never change weights
+		For(&v1beta1.ServiceLoadbalancingPreference{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: newUnstructuredHTTPRoute()},
+			handler.EnqueueRequestsFromMapFunc(mapHTTPRouteToSLP),
+		).
+		Complete(r)
+}
+
+// mapHTTPRouteToSLP returns the request for the SLP specified in the HTTPRoute annotation.
+func mapHTTPRouteToSLP(o client.Object) []reconcile.Request {
+	name, ok := o.GetAnnotations()[v1beta1.SLPAnnotation]
+	if !ok || name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: name}}}
+}
+
+// Reconcile moves the current state of the cluster closer to the desired state.
+func (r *SLPHTTPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("Reconcile")
+
+	// get SLP
+	slp := &v1beta1.ServiceLoadbalancingPreference{}
+	err := r.Get(ctx, req.NamespacedName, slp)
+	if errors.IsNotFound(err) {
+		lg.Info("SLP is already deleted")
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		lg.Error(err, "unable to get SLP")
+		return ctrl.Result{}, err
+	}
+
+	// list HTTPRoutes referring to the SLP
+	ul := &unstructured.UnstructuredList{}
+	ul.SetGroupVersionKind(httpRouteGVK.GroupVersion().WithKind(httpRouteGVK.Kind + "List"))
+	if err := r.List(ctx, ul, client.InNamespace(slp.Namespace)); err != nil {
+		lg.Error(err, "unable to list HTTPRoutes")
+		return ctrl.Result{}, err
+	}
+	applied := 0
+	equal := false
+	for i := range ul.Items {
+		route := &ul.Items[i]
+		if route.GetAnnotations()[v1beta1.SLPAnnotation] != slp.Name {
+			continue
+		}
+		orig := route.DeepCopy()
+		changed, fallback, err := setHTTPRouteBackendWeights(route, slp.Name+"-", slp.Spec.Clusters)
+		if err != nil {
+			lg.Error(err, "unable to set HTTPRoute backend weights", "httproute", route.GetName())
+			return ctrl.Result{}, err
+		}
+		if changed {
+			// NOTE: the merge patch replaces the whole spec.rules, so ensure that it is not based on a stale HTTPRoute
+			err := r.Patch(ctx, route, client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{}))
+			if errors.IsConflict(err) {
+				lg.Info("HTTPRoute has been modified, requeue", "httproute", route.GetName())
+				return ctrl.Result{Requeue: true}, nil
+			}
+			if err != nil {
+				lg.Error(err, "unable to patch HTTPRoute", "httproute", route.GetName())
+				return ctrl.Result{}, err
+			}
+			lg.Info("HTTPRoute backend weights updated", "httproute", route.GetName())
+		}
+		if fallback {
+			lg.Info("all clusters have weight 0, fall back to equal weights", "httproute", route.GetName())
+			equal = true
+		}
+		applied++
+	}
+	if applied == 0 {
+		lg.Info("no HTTPRoute refers to the SLP")
+		return ctrl.Result{}, nil
+	}
+
+	// acknowledge the applied generation
+	if slp.IsApplied() {
+		return ctrl.Result{}, nil
+	}
+	orig := slp.DeepCopy()
+	slp.Status.Consumer = &v1beta1.SLPConsumerStatus{
+		Name:              r.ControllerName,
+		AppliedGeneration: slp.Generation,
+		LastAppliedTime:   metav1.Now(),
+	}
+	if equal {
+		slp.Status.Consumer.Message = "all clusters have weight 0, so the backends are given equal weights"
+	}
+	if err := r.Status().Patch(ctx, slp, client.MergeFrom(orig)); err != nil {
+		lg.Error(err, "unable to update SLP status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// conflictingClient updates the object before the first patch as if another writer did.
+type conflictingClient struct {
+	client.Client
+	conflicted bool
+}
+
+func (c *conflictingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if !c.conflicted {
+		c.conflicted = true
+		cur := newUnstructuredHTTPRoute()
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), cur); err != nil {
+			return err
+		}
+		cur.SetLabels(map[string]string{"foo": "bar"})
+		if err := c.Update(ctx, cur); err != nil {
+			return err
+		}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func Test_SLPHTTPRouteReconciler_Reconcile_conflict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+	slp := &v1beta1.ServiceLoadbalancingPreference{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc", Generation: 1},
+		Spec:       v1beta1.ServiceLoadbalancingPreferenceSpec{Clusters: map[string]v1beta1.ClusterPreferences{"c1": {Weight: 1}}},
+	}
+	route := helperHTTPRoute(map[string]any{"name": "svc-c1"})
+	route.SetNamespace("default")
+	route.SetName("route")
+	route.SetAnnotations(map[string]string{v1beta1.SLPAnnotation: "svc"})
+	c := &conflictingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(slp, route).Build()}
+	r := &SLPHTTPRouteReconciler{Client: c, Scheme: scheme}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "svc"}}
+	ctx := context.Background()
+
+	res, err := r.Reconcile(ctx, req)
+	if err != nil || !res.Requeue {
+		t.Fatalf("Reconcile() = %v, %v, want requeue on conflict", res, err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	got := newUnstructuredHTTPRoute()
+	if err := c.Get(ctx, client.ObjectKeyFromObject(route), got); err != nil {
+		t.Fatal(err)
+	}
+	rules, _, _ := unstructured.NestedSlice(got.Object, "spec", "rules")
+	w, _, _ := unstructured.NestedInt64(rules[0].(map[string]any)["backendRefs"].([]any)[0].(map[string]any), "weight")
+	if w != 1 || got.GetLabels()["foo"] != "bar" {
+		t.Errorf("HTTPRoute = %v, want weight 1 and the concurrent change kept", got.Object)
+	}
+}
+
+func Test_SLPHTTPRouteReconciler_Reconcile_allZero(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1beta1.AddToScheme(scheme)
+	slp := &v1beta1.ServiceLoadbalancingPreference{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "svc", Generation: 1},
+		Spec:       v1beta1.ServiceLoadbalancingPreferenceSpec{Clusters: map[string]v1beta1.ClusterPreferences{"*": {Weight: 0}}},
+	}
+	route := helperHTTPRoute(map[string]any{"name": "svc-c1"}, map[string]any{"name": "svc-c2"})
+	route.SetNamespace("default")
+	route.SetName("route")
+	route.SetAnnotations(map[string]string{v1beta1.SLPAnnotation: "svc"})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(slp, route).Build()
+	r := &SLPHTTPRouteReconciler{Client: c, Scheme: scheme}
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(slp)}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	gotRoute := newUnstructuredHTTPRoute()
+	if err := c.Get(ctx, client.ObjectKeyFromObject(route), gotRoute); err != nil {
+		t.Fatal(err)
+	}
+	want := helperHTTPRoute(map[string]any{"name": "svc-c1", "weight": int64(1)}, map[string]any{"name": "svc-c2", "weight": int64(1)})
+	if diff := cmp.Diff(gotRoute.Object["spec"], want.Object["spec"]); diff != "" {
+		t.Errorf("HTTPRoute spec = %v, want %v, diff %s", gotRoute.Object["spec"], want.Object["spec"], diff)
+	}
+	gotSLP := &v1beta1.ServiceLoadbalancingPreference{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(slp), gotSLP); err != nil {
+		t.Fatal(err)
+	}
+	if !gotSLP.IsApplied() || gotSLP.Status.Consumer.Message == "" {
+		t.Errorf("SLP status.consumer = %+v, want applied with the reason of equal weights", gotSLP.Status.Consumer)
+	}
+}
@@ -4,12 +4,23 @@ import (
 	"context"
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
 
 	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
@@ -23,9 +34,12 @@ type SLPOptimizerReconciler struct {
 	ControllerName string
 }
 
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core.kubefed.io,resources=kubefedclusters,verbs=get;list;watch
 //+kubebuilder:rbac:groups=types.kubefed.io,resources=federatedservices,verbs=get;list;watch
+//+kubebuilder:rbac:groups=types.kubefed.io,resources=federateddeployments,verbs=get;list;watch
 //+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=serviceloadbalancingpreferences,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=serviceloadbalancingpreferences/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=waofedconfigs,verbs=get;list;watch
 
 // SetupWithManager sets up the controller with the Manager.
@@ -34,10 +48,160 @@ func (r *SLPOptimizerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(newUnstructuredFederatedService()).
-		Owns(&v1beta1.ServiceLoadbalancingPreference{}).
+		// status updates (by SLPOptimizer itself or loadbalancer controllers) never change optimization results
+		Owns(&v1beta1.ServiceLoadbalancingPreference{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &fedcorev1b1.KubeFedCluster{}},
+			handler.EnqueueRequestsFromMapFunc(r.mapKubeFedClusterToFederatedServices),
+			builder.WithPredicates(kubeFedClusterPredicate),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToFederatedServices),
+			builder.WithPredicates(namespacePredicate),
+		).
+		Watches(
+			&source.Kind{Type: &v1beta1.WAOFedConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.mapWAOFedConfigToFederatedServices),
+			builder.WithPredicates(waoFedConfigPredicate),
+		).
 		Complete(r)
 }
 
+// mapKubeFedClusterToFederatedServices returns requests for FederatedServices
+// that are selected by WAOFedConfig and may be placed on the KubeFedCluster.
+// Ref. rspOptimizerKindReconciler.mapKubeFedClusterToFederatedDeployments (same implementation)
+func (r *SLPOptimizerReconciler) mapKubeFedClusterToFederatedServices(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("kubefedcluster", client.ObjectKeyFromObject(o))
+
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+	if wfc.Spec.LoadBalancing == nil || o.GetNamespace() != wfc.Spec.KubeFedNamespace {
+		return nil
+	}
+
+	items, err := listFederatedObjects(ctx, r.Client, federatedServiceGVK)
+	if err != nil {
+		lg.Error(err, "unable to list FederatedServices")
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range items {
+		fsvc, err := convertToStructuredFederatedService(&items[i])
+		if err != nil {
+			continue
+		}
+		selected, err := matchResourceSelector(ctx, r.Client, wfc.Spec.LoadBalancing.Selector, fsvc)
+		if err != nil {
+			lg.Error(err, "unable to match resource selector", "obj", client.ObjectKeyFromObject(&items[i]))
+			continue
+		}
+		if !selected {
+			continue
+		}
+		if !placementMayInclude(fsvc.Spec.Placement, o.GetName()) {
+			continue
+		}
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&items[i])})
+	}
+	lg.Info("KubeFedCluster changed", "requests", len(reqs))
+	return reqs
+}
+
+// mapWAOFedConfigToFederatedServices returns requests for FederatedServices that are selected by WAOFedConfig
+// and FederatedServices that own SLPs created by SLPOptimizer (so that the SLPs can be deleted if no longer selected).
+// Ref. rspOptimizerKindReconciler.mapWAOFedConfigToFederatedDeployments (same implementation)
+func (r *SLPOptimizerReconciler) mapWAOFedConfigToFederatedServices(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("waofedconfig", client.ObjectKeyFromObject(o))
+
+	if o.GetName() != v1beta1.WAOFedConfigName {
+		return nil
+	}
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+
+	reqs := map[types.NamespacedName]struct{}{}
+
+	// SLPs created by SLPOptimizer have the same namespace and name as the owner FederatedService
+	prefs := &v1beta1.ServiceLoadbalancingPreferenceList{}
+	if err := r.List(ctx, prefs, client.MatchingLabels{"app.kubernetes.io/created-by": r.ControllerName}); err != nil {
+		lg.Error(err, "unable to list SLPs")
+		return nil
+	}
+	for _, pref := range prefs.Items {
+		reqs[client.ObjectKeyFromObject(&pref)] = struct{}{}
+	}
+
+	if wfc.Spec.LoadBalancing != nil {
+		items, err := listFederatedObjects(ctx, r.Client, federatedServiceGVK)
+		if err != nil {
+			lg.Error(err, "unable to list FederatedServices")
+			return nil
+		}
+		for i := range items {
+			fsvc, err := convertToStructuredFederatedService(&items[i])
+			if err != nil {
+				continue
+			}
+			selected, err := matchResourceSelector(ctx, r.Client, wfc.Spec.LoadBalancing.Selector, fsvc)
+			if err != nil {
+				lg.Error(err, "unable to match resource selector", "obj", client.ObjectKeyFromObject(&items[i]))
+				continue
+			}
+			if selected {
+				reqs[client.ObjectKeyFromObject(&items[i])] = struct{}{}
+			}
+		}
+	}
+
+	lg.Info("WAOFedConfig changed", "requests", len(reqs))
+	return toRequests(reqs)
+}
+
+// mapNamespaceToFederatedServices returns requests for all FederatedServices in the Namespace
+// if WAOFedConfig selects them by the namespace labels.
+// Ref. rspOptimizerKindReconciler.mapNamespaceToFederatedDeployments (same implementation)
+func (r *SLPOptimizerReconciler) mapNamespaceToFederatedServices(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+	lg := log.FromContext(ctx).WithName(r.ControllerName).WithValues("namespace", o.GetName())
+
+	wfc, err := getWAOFedConfig(ctx, r.Client)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			lg.Error(err, "unable to get WAOFedConfig")
+		}
+		return nil
+	}
+	if wfc.Spec.LoadBalancing == nil || wfc.Spec.LoadBalancing.Selector.NamespaceSelector == nil {
+		return nil
+	}
+
+	// NOTE: unselected ones are also requested so that their SLPs can be deleted
+	items, err := listFederatedObjects(ctx, r.Client, federatedServiceGVK, client.InNamespace(o.GetName()))
+	if err != nil {
+		lg.Error(err, "unable to list federated objects", "gvk", federatedServiceGVK)
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(items))
+	for i := range items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&items[i])})
+	}
+	lg.Info("Namespace changed", "requests", len(reqs))
+	return reqs
+}
+
 // Reconcile moves the current state of the cluster closer to the desired state.
 func (r *SLPOptimizerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	lg := log.FromContext(ctx)
@@ -56,8 +220,8 @@ func (r *SLPOptimizerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 	if wfc.Spec.LoadBalancing == nil {
-		lg.Info("WAOFedConfig spec.loadbalancing is nil, drop the request")
-		return ctrl.Result{}, nil
+		// SLPs created by SLPOptimizer should be deleted as no FederatedService is selected
+		lg.Info("WAOFedConfig spec.loadbalancing is nil")
 	}
 
 	// get FederatedService
@@ -77,29 +241,50 @@ func (r *SLPOptimizerReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, err
 	}
 
+	// check whether the FederatedService is selected
+	selected := false
+	if wfc.Spec.LoadBalancing != nil {
+		selected, err = matchResourceSelector(ctx, r.Client, wfc.Spec.LoadBalancing.Selector, fsvc)
+		if err != nil {
+			lg.Error(err, "unable to match resource selector")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// merge per-object optimizer overrides into the settings
+	// NOTE: wfc is fetched for this request only, so it is safe to overwrite
+	if selected {
+		settings, err := wfc.Spec.LoadBalancing.Optimizer.WithOverrides(fsvc.Annotations)
+		if err != nil {
+			lg.Error(err, "invalid SLPOptimizer override annotations, use WAOFedConfig settings")
+		} else {
+			wfc.Spec.LoadBalancing.Optimizer = settings
+		}
+	}
+
 	// reconcile SLP
-	if err := r.reconcileLSP(ctx, fsvc, wfc); err != nil {
+	if err := r.reconcileLSP(ctx, fsvc, wfc, selected); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	// requeue to re-optimize cluster weights periodically
+	if selected {
+		if d := wfc.Spec.LoadBalancing.Optimizer.ReoptimizeInterval; d != nil && d.Duration > 0 {
+			lg.Info("requeue to re-optimize", "after", d.Duration)
+			return ctrl.Result{RequeueAfter: d.Duration}, nil
+		}
+	}
+
 	return ctrl.Result{}, nil
 }
 
 func (r *SLPOptimizerReconciler) reconcileLSP(
-	ctx context.Context, fsvc *structuredFederatedService, wfc *v1beta1.WAOFedConfig,
+	ctx context.Context, fsvc *structuredFederatedService, wfc *v1beta1.WAOFedConfig, selected bool,
 ) error {
 	lg := log.FromContext(ctx)
 	lg.Info("reconcileRSP")
 
-	skip := true
-	if *wfc.Spec.LoadBalancing.Selector.Any {
-		// check selector.any
-		skip = false
-	} else if _, ok := fsvc.GetAnnotations()[*wfc.Spec.LoadBalancing.Selector.HasAnnotation]; ok {
-		// check SLPOptimizer annotation exists in the FederatedService
-		// currently the value is ignored
-		skip = false
-	}
+	skip := !selected
 
 	if skip {
 		// delete the associated SLP if no annotation in the FederatedService
@@ -142,6 +327,8 @@ func (r *SLPOptimizerReconciler) reconcileLSP(
 		slp := &v1beta1.ServiceLoadbalancingPreference{}
 		slp.SetNamespace(fsvc.Namespace)
 		slp.SetName(fsvc.Name)
+		var method v1beta1.SLPOptimizerMethod
+		var excluded map[string]string
 		op, err := ctrl.CreateOrUpdate(ctx, r.Client, slp, func() error {
 			slp.Labels = map[string]string{
 				"app.kubernetes.io/created-by": r.ControllerName,
@@ -150,10 +337,11 @@ func (r *SLPOptimizerReconciler) reconcileLSP(
 				Clusters: nil,
 			}
 			lg.Info("optimize cluster weights", "method", wfc.Spec.LoadBalancing.Optimizer.Method)
-			clusters, err := r.optimizeClusterWeights(ctx, fsvc, wfc)
+			clusters, m, ex, err := r.optimizeClusterWeights(ctx, fsvc, wfc)
 			if err != nil {
 				return err
 			}
+			method, excluded = m, ex
 			slp.Spec.Clusters = clusters
 			if err := fsvc.setControllerReference(slp); err != nil {
 				return err
@@ -162,8 +350,24 @@ func (r *SLPOptimizerReconciler) reconcileLSP(
 		})
 		if err != nil {
 			lg.Error(err, "unable to create or update SLP")
+			return err
 		}
 		lg.Info("SLP operated", "op", op)
+
+		// record how spec.clusters was computed
+		// NOTE: patch only status.optimizer as status.consumer is owned by loadbalancer controllers
+		orig := slp.DeepCopy()
+		slp.Status.Optimizer = &v1beta1.SLPOptimizerStatus{
+			Name:               r.ControllerName,
+			Method:             method,
+			ObservedGeneration: slp.Generation,
+			LastOptimizedTime:  metav1.Now(),
+			ExcludedClusters:   excluded,
+		}
+		if err := r.Status().Patch(ctx, slp, client.MergeFrom(orig)); err != nil {
+			lg.Error(err, "unable to update SLP status")
+			return err
+		}
 	}
 
 	return nil
@@ -171,7 +375,7 @@ func (r *SLPOptimizerReconciler) reconcileLSP(
 
 func (r *SLPOptimizerReconciler) optimizeClusterWeights(
 	ctx context.Context, fsvc *structuredFederatedService, wfc *v1beta1.WAOFedConfig,
-) (map[string]v1beta1.ClusterPreferences, error) {
+) (map[string]v1beta1.ClusterPreferences, v1beta1.SLPOptimizerMethod, map[string]string, error) {
 	lg := log.FromContext(ctx)
 	lg.Info("optimizeClusterWeights", "wfc", wfc, "fsvc", fsvc)
 
@@ -179,82 +383,208 @@ func (r *SLPOptimizerReconciler) optimizeClusterWeights(
 
 	if fsvc.Spec.Placement == nil || (fsvc.Spec.Placement.Clusters == nil && fsvc.Spec.Placement.ClusterSelector == nil) {
 		lg.Info("no loadbalancing as spec.placement == nil", "spec.placement", fsvc.Spec.Placement)
-		return map[string]v1beta1.ClusterPreferences{}, nil
+		return map[string]v1beta1.ClusterPreferences{}, *wfc.Spec.LoadBalancing.Optimizer.Method, nil, nil
 	}
 
-	var candidates []string
-	if fsvc.Spec.Placement.Clusters != nil {
-		lg.Info("placement.clusters found", "fsvc", fsvc.Spec.Placement.Clusters)
-		for _, c := range fsvc.Spec.Placement.Clusters {
-			candidates = append(candidates, c.Name)
+	clusters, excluded, err := listCandidateClusters(ctx, r.Client, fsvc.Spec.Placement, wfc.Spec.KubeFedNamespace)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	lg.Info("available clusters", "clusters", clusters)
+
+	settings := wfc.Spec.LoadBalancing.Optimizer
+	if d := settings.WAOEstimatorDiscovery; d != nil && slpOptimizerUsesWAOEstimators(settings) {
+		estimators, err := listWAOEstimators(ctx, r.Client, wfc.Spec.KubeFedNamespace, settings.WAOEstimators, d)
+		if err != nil {
+			return nil, "", nil, err
 		}
-	} else {
-		lg.Info("placement.clusterSelector found", "spec.placement.clusterSelector", fsvc.Spec.Placement.ClusterSelector)
-		sel, err := metav1.LabelSelectorAsSelector(fsvc.Spec.Placement.ClusterSelector)
+		settings = settings.DeepCopy()
+		settings.WAOEstimators = estimators
+	}
+
+	// try the method and the fallback methods in order until one succeeds
+	var errs []error
+	for _, method := range settings.Methods() {
+		optimizeFn, ok := slpOptimizeFuncCollection[method]
+		if !ok {
+			errs = append(errs, fmt.Errorf("invalid method \"%v\"", method))
+			continue
+		}
+		cps, err := optimizeFn(ctx, r.Client, clusters, settings, fsvc)
 		if err != nil {
-			lg.Error(err, "placement.clusterSelector")
-			return nil, err
+			lg.Error(err, "method failed, try the next fallback method if any", "method", method)
+			errs = append(errs, fmt.Errorf("method %s: %w", method, err))
+			continue
 		}
-		cl := &fedcorev1b1.KubeFedClusterList{}
-		if err := r.List(ctx, cl, &client.ListOptions{
-			Namespace:     wfc.Spec.KubeFedNamespace,
-			LabelSelector: sel,
-		}); err != nil {
-			return nil, err
+		lg.Info("optimize weights", "method", method, "weights", cps)
+		return cps, method, excluded, nil
+	}
+	if len(errs) == 0 {
+		return nil, "", nil, fmt.Errorf("no method specified")
+	}
+	return nil, "", nil, utilerrors.NewAggregate(errs)
+}
+
+type slpOptimizeFunc func(ctx context.Context, c client.Reader, clusters []string, settings *v1beta1.SLPOptimizerSettings, fsvc *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error)
+
+var slpOptimizeFuncCollection = map[v1beta1.SLPOptimizerMethod]slpOptimizeFunc{
+	v1beta1.SLPOptimizerMethodRoundRobin: slpOptimizeFnRoundRobin,
+	v1beta1.SLPOptimizerMethodWAO:        slpOptimizeFnWAO,
+	v1beta1.SLPOptimizerMethodWebhook:    slpOptimizeFnWebhook,
+}
+
+// slpOptimizerUsesWAOEstimators reports whether the method or the fallback methods of the settings calls WAO-Estimators.
+func slpOptimizerUsesWAOEstimators(settings *v1beta1.SLPOptimizerSettings) bool {
+	if settings == nil {
+		return false
+	}
+	for _, m := range settings.Methods() {
+		if m == v1beta1.SLPOptimizerMethodWAO {
+			return true
 		}
-		for _, c := range cl.Items {
-			candidates = append(candidates, c.Name)
+	}
+	return false
+}
+
+func slpOptimizeFnRoundRobin(_ context.Context, _ client.Reader, clusters []string, _ *v1beta1.SLPOptimizerSettings, _ *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error) {
+	cps := make(map[string]v1beta1.ClusterPreferences, len(clusters))
+	for _, cl := range clusters {
+		cps[cl] = v1beta1.ClusterPreferences{
+			Weight: 1,
 		}
 	}
+	return cps, nil
+}
+
+// slpOptimizeFnWAO weights clusters by the least power allocation of the workloads behind the FederatedService.
+// The workloads are FederatedDeployments in the same namespace whose pod template labels match the service selector,
+// and each cluster gets the number of pods that WAO-Estimators recommend to allocate on it as its weight.
+func slpOptimizeFnWAO(ctx context.Context, c client.Reader, clusters []string, settings *v1beta1.SLPOptimizerSettings, fsvc *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("slpOptimizeFnWAO")
+
+	if fsvc == nil || fsvc.Spec == nil || fsvc.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fsvc: fsvc == nil || fsvc.Spec == nil || fsvc.Spec.Template == nil")
+	}
 
-	var clusters []string
-	cl := &fedcorev1b1.KubeFedClusterList{}
-	if err := r.List(ctx, cl, &client.ListOptions{
-		Namespace: wfc.Spec.KubeFedNamespace,
-	}); err != nil {
+	fdeploys, err := listBackendFederatedDeployments(ctx, c, fsvc)
+	if err != nil {
 		return nil, err
 	}
-	registered := map[string]struct{}{}
-	for _, c := range cl.Items {
-		registered[c.Name] = struct{}{}
+	if len(fdeploys) == 0 {
+		return nil, fmt.Errorf("no FederatedDeployment selected by FederatedService %s/%s", fsvc.Namespace, fsvc.Name)
 	}
-	dedup := map[string]int{}
-	for _, c := range candidates {
-		if _, ok := registered[c]; !ok {
-			continue
-		}
-		dedup[c] += 1
-		if dedup[c] == 1 {
-			clusters = append(clusters, c)
+
+	cpuMilli, replicas := waoWorkloads(ctx, fdeploys)
+	lg.Info("backend workloads", "fdeploys", len(fdeploys), "cpuMilli", cpuMilli, "replicas", replicas)
+
+	weights, err := computeLeastCostWeightsWAO(ctx, c, clusters, settings.WAOEstimators, cpuMilli, replicas, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	cps := make(map[string]v1beta1.ClusterPreferences, len(clusters))
+	for i, c := range clusters {
+		cps[c] = v1beta1.ClusterPreferences{
+			Weight: int64(weights[i]),
 		}
 	}
-	lg.Info("available clusters", "clusters", clusters)
 
-	optimizeFn, ok := slpOptimizeFuncCollection[*wfc.Spec.LoadBalancing.Optimizer.Method]
-	if !ok {
-		return nil, fmt.Errorf("invalid method \"%v\"", wfc.Spec.LoadBalancing.Optimizer.Method)
+	return cps, nil
+}
+
+// slpOptimizeFnWebhook delegates the optimization to the external optimizer specified by spec.loadbalancing.optimizer.webhook.
+// The request contains the workloads behind the FederatedService (Ref. slpOptimizeFnWAO), and clusters not returned get no access.
+func slpOptimizeFnWebhook(ctx context.Context, c client.Reader, clusters []string, settings *v1beta1.SLPOptimizerSettings, fsvc *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("slpOptimizeFnWebhook")
+
+	if fsvc == nil || fsvc.Spec == nil || fsvc.Spec.Template == nil {
+		return nil, fmt.Errorf("wrong fsvc: fsvc == nil || fsvc.Spec == nil || fsvc.Spec.Template == nil")
 	}
-	cps, err := optimizeFn(ctx, clusters, wfc.Spec.LoadBalancing.Optimizer, fsvc)
+	if settings.Webhook == nil {
+		return nil, fmt.Errorf("webhook is not specified")
+	}
+
+	fdeploys, err := listBackendFederatedDeployments(ctx, c, fsvc)
 	if err != nil {
 		return nil, err
 	}
-	lg.Info("optimize weights", "weights", cps)
+	requests, replicas := aggregateWorkloads(fdeploys, false)
+	lg.Info("backend workloads", "fdeploys", len(fdeploys), "requests", requests, "replicas", replicas)
+
+	req, err := newOptimizeRequest(v1beta1.OptimizeTypeLoadBalancing, clusters, fsvc, int32(replicas), requests)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := callOptimizerWebhook(ctx, settings.Webhook, req)
+	if err != nil {
+		return nil, err
+	}
+	lg.Info("webhook response", "clusters", resp.Clusters)
+
+	cps := make(map[string]v1beta1.ClusterPreferences, len(resp.Clusters))
+	for _, cl := range clusters {
+		if p, ok := resp.Clusters[cl]; ok {
+			cps[cl] = v1beta1.ClusterPreferences{
+				Weight: p.Weight,
+			}
+		}
+	}
 	return cps, nil
 }
 
-type slpOptimizeFunc func(ctx context.Context, clusters []string, settings *v1beta1.SLPOptimizerSettings, fsvc *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error)
+// listBackendFederatedDeployments returns FederatedDeployments whose pod template labels match the FederatedService selector.
+func listBackendFederatedDeployments(ctx context.Context, c client.Reader, fsvc *structuredFederatedService) ([]*structuredFederatedDeployment, error) {
+	// a Service without selectors has no backend Pods managed by Kubernetes
+	if len(fsvc.Spec.Template.Spec.Selector) == 0 {
+		return nil, nil
+	}
+	sel := labels.SelectorFromSet(fsvc.Spec.Template.Spec.Selector)
 
-var slpOptimizeFuncCollection = map[v1beta1.SLPOptimizerMethod]slpOptimizeFunc{
-	v1beta1.SLPOptimizerMethodRoundRobin: slpOptimizeFnRoundRobin,
-	// v1beta1.SLPOptimizerMethodWAO: slpOptimizeFnWAO, // TODO
+	ul := &unstructured.UnstructuredList{}
+	ul.SetGroupVersionKind(federatedDeploymentGVK.GroupVersion().WithKind(federatedDeploymentGVK.Kind + "List"))
+	if err := c.List(ctx, ul, client.InNamespace(fsvc.Namespace)); err != nil {
+		return nil, err
+	}
+
+	var fdeploys []*structuredFederatedDeployment
+	for i := range ul.Items {
+		fdeploy, err := convertToStructuredFederatedDeployment(&ul.Items[i])
+		if err != nil || fdeploy.Spec.Template == nil {
+			continue
+		}
+		if sel.Matches(labels.Set(fdeploy.Spec.Template.Spec.Template.Labels)) {
+			fdeploys = append(fdeploys, fdeploy)
+		}
+	}
+	return fdeploys, nil
 }
 
-func slpOptimizeFnRoundRobin(_ context.Context, clusters []string, _ *v1beta1.SLPOptimizerSettings, _ *structuredFederatedService) (map[string]v1beta1.ClusterPreferences, error) {
-	cps := make(map[string]v1beta1.ClusterPreferences, len(clusters))
-	for _, cl := range clusters {
-		cps[cl] = v1beta1.ClusterPreferences{
-			Weight: 1,
+// aggregateWorkloads returns the total replicas of the given FederatedDeployments and
+// the resources per replica (averaged over all replicas, rounded up). Ref. podResources, templateReplicas
+func aggregateWorkloads(fdeploys []*structuredFederatedDeployment, nonZero bool) (requests corev1.ResourceList, replicas int) {
+	total := map[corev1.ResourceName]int64{}
+	for _, fdeploy := range fdeploys {
+		r := int(fdeploy.templateReplicas())
+		replicas += r
+		rl := podResources(&fdeploy.Spec.Template.Spec.Template.Spec, nonZero)
+		for _, m := range podResourceModels {
+			q := rl[m.name]
+			total[m.name] += q.MilliValue() * int64(r)
 		}
 	}
-	return cps, nil
+	requests = make(corev1.ResourceList, len(podResourceModels))
+	for _, m := range podResourceModels {
+		v := int64(0)
+		if replicas > 0 {
+			v = (total[m.name] + int64(replicas) - 1) / int64(replicas)
+		}
+		if m.milli {
+			requests[m.name] = *resource.NewMilliQuantity(v, m.format)
+		} else {
+			requests[m.name] = *resource.NewQuantity((v+999)/1000, m.format)
+		}
+	}
+	return requests, replicas
 }
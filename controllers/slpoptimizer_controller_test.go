@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_slpOptimizeFnRoundRobin(t *testing.T) {
+	type args struct {
+		clusters []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]v1beta1.ClusterPreferences
+		wantErr bool
+	}{
+		{"empty", args{[]string{}}, map[string]v1beta1.ClusterPreferences{}, false},
+		{"1cluster", args{[]string{"c1"}}, map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 1},
+		}, false},
+		{"3clusters", args{[]string{"c1", "c2", "c3"}}, map[string]v1beta1.ClusterPreferences{
+			"c1": {Weight: 1},
+			"c2": {Weight: 1},
+			"c3": {Weight: 1},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := slpOptimizeFnRoundRobin(context.Background(), nil, tt.args.clusters, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("slpOptimizeFnRoundRobin() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if cmp.Diff(got, tt.want) != "" {
+				t.Errorf("slpOptimizeFnRoundRobin() = %v, want %v, diff %s", got, tt.want, cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func helperFederatedDeploymentWithCPU(replicas *int32, cpuRequests ...string) *structuredFederatedDeployment {
+	var containers []corev1.Container
+	for _, cpu := range cpuRequests {
+		c := corev1.Container{}
+		if cpu != "" {
+			c.Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		}
+		containers = append(containers, c)
+	}
+	return &structuredFederatedDeployment{
+		Spec: &structuredFederatedDeploymentSpec{
+			Template: &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{
+					Replicas: replicas,
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{Containers: containers},
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_aggregateWorkloads(t *testing.T) {
+	tests := []struct {
+		name         string
+		fdeploys     []*structuredFederatedDeployment
+		nonZero      bool
+		wantCPUMilli int64
+		wantMemory   string
+		wantReplicas int
+	}{
+		{"empty", nil, false, 0, "0", 0},
+		{"1fdeploy", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(3), "100m", "200m"),
+		}, false, 300, "0", 3},
+		{"no_requests", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(2), ""),
+		}, false, 0, "0", 2},
+		{"no_requests_nonzero", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(2), ""),
+		}, true, 100, "200Mi", 2},
+		{"nil_replicas", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(nil, "100m"),
+		}, false, 100, "0", 1},
+		{"2fdeploys", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(1), "100m"),
+			helperFederatedDeploymentWithCPU(pointer.Int32(2), "250m"),
+		}, false, 200, "0", 3},
+		{"2fdeploys_nonzero", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(1), "400m"),
+			helperFederatedDeploymentWithCPU(pointer.Int32(3), ""),
+		}, true, 175, "200Mi", 4},
+		{"round_up", []*structuredFederatedDeployment{
+			helperFederatedDeploymentWithCPU(pointer.Int32(2), "100m"),
+			helperFederatedDeploymentWithCPU(pointer.Int32(1), "101m"),
+		}, false, 101, "0", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotReplicas := aggregateWorkloads(tt.fdeploys, tt.nonZero)
+			if got.Cpu().MilliValue() != tt.wantCPUMilli || got.Memory().Cmp(resource.MustParse(tt.wantMemory)) != 0 || gotReplicas != tt.wantReplicas {
+				t.Errorf("aggregateWorkloads() = (%v, %v), want (%vm cpu, %v memory, %v)", got, gotReplicas, tt.wantCPUMilli, tt.wantMemory, tt.wantReplicas)
+			}
+		})
+	}
+}
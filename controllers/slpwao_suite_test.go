@@ -0,0 +1,80 @@
+//go:build testOnExistingClusterSLPWAO
+
+package controllers_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+var (
+	testWFCSLPWAO1 = v1beta1.WAOFedConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+		},
+		Spec: v1beta1.WAOFedConfigSpec{
+			KubeFedNamespace: testKubeFedNS,
+			Scheduling:       nil,
+			LoadBalancing: &v1beta1.LoadBalancingSettings{
+				Selector: &v1beta1.ResourceSelector{
+					Any: pointer.Bool(true),
+				},
+				Optimizer: &v1beta1.SLPOptimizerSettings{
+					Method: (*v1beta1.SLPOptimizerMethod)(pointer.String(v1beta1.SLPOptimizerMethodWAO)),
+					WAOEstimators: map[string]*v1beta1.WAOEstimatorSetting{
+						"kind-waofed-test-0": {
+							Endpoint:  "http://localhost:5657",
+							Namespace: "default",
+							Name:      "default",
+						},
+						"kind-waofed-test-1": {
+							Endpoint:  "http://localhost:5658",
+							Namespace: "default",
+							Name:      "default",
+						},
+					},
+				},
+			},
+		},
+	}
+)
+
+var _ = Describe("SLPOptimizer (wao method)", func() {
+
+	BeforeEach(slpOptimizerBeforeEachFn)
+	AfterEach(slpOptimizerAfterEachFn)
+
+	Context("loadbalance on clusters (SLPWAO)", func() {
+		type cps map[string]v1beta1.ClusterPreferences
+		const (
+			c1 = "kind-waofed-test-0"
+			c2 = "kind-waofed-test-1"
+		)
+		It("should be weighted on", func() {
+			ctx := context.Background()
+
+			// create the backend FederatedDeployment
+			fdeploy, _, _, err := helperLoadYAML(filepath.Join("testdata", "slpwao", "fdeploy17.yaml"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = k8sDynamicClient.Resource(federatedDeploymentGVR).Namespace(testNS).Create(ctx, fdeploy, metav1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			defer func() {
+				err := k8sDynamicClient.Resource(federatedDeploymentGVR).Namespace(testNS).Delete(ctx, fdeploy.GetName(), metav1.DeleteOptions{})
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			// NOTE: use the first pattern at this time (same as RSPWAO)
+			// [[0 9] [1 8] [2 7] [3 6] [4 5] [5 4] [6 3] [7 2] [8 1] [9 0]]
+			testSLP(testWFCSLPWAO1, testNS, filepath.Join("testdata", "slpwao", "fsvc17.yaml"),
+				cps{c1: {Weight: 0}, c2: {Weight: 9}})
+		})
+	})
+})
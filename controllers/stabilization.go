@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// rspAppliedWeights represents the cluster weights last applied to an RSP.
+type rspAppliedWeights struct {
+	weights map[string]int64
+	time    time.Time
+}
+
+// getRSPAppliedWeights returns the cluster weights recorded in the RSP annotations,
+// or nil if not recorded or invalid (e.g. edited by users).
+func getRSPAppliedWeights(annotations map[string]string) *rspAppliedWeights {
+	w, ok := annotations[v1beta1.RSPLastAppliedWeightsAnnotation]
+	if !ok {
+		return nil
+	}
+	t, ok := annotations[v1beta1.RSPLastAppliedTimeAnnotation]
+	if !ok {
+		return nil
+	}
+	out := &rspAppliedWeights{}
+	if err := json.Unmarshal([]byte(w), &out.weights); err != nil {
+		return nil
+	}
+	var err error
+	if out.time, err = time.Parse(time.RFC3339, t); err != nil {
+		return nil
+	}
+	return out
+}
+
+// setRSPAppliedWeights records the cluster weights in the RSP annotations.
+func setRSPAppliedWeights(annotations map[string]string, weights map[string]int64, t time.Time) error {
+	b, err := json.Marshal(weights)
+	if err != nil {
+		return err
+	}
+	annotations[v1beta1.RSPLastAppliedWeightsAnnotation] = string(b)
+	annotations[v1beta1.RSPLastAppliedTimeAnnotation] = t.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// clusterWeights returns the weight of each cluster.
+func clusterWeights(cps map[string]fedschedv1a1.ClusterPreferences) map[string]int64 {
+	out := make(map[string]int64, len(cps))
+	for k, v := range cps {
+		out[k] = v.Weight
+	}
+	return out
+}
+
+func sameKeys(a, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sameWeights(a, b map[string]int64) bool {
+	if !sameKeys(a, b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// newPatternCostFunc returns a function that estimates the cost of allocating weights[clusters[i]] workloads on clusters[i],
+// where costs[i][n-1] is the cost of allocating n workloads on clusters[i].
+// The function returns false if the allocation cannot be estimated, e.g. it includes other clusters or costs +Inf.
+func newPatternCostFunc(clusters []string, costs [][]float64) func(weights map[string]int64) (float64, bool) {
+	index := make(map[string]int, len(clusters))
+	for i, c := range clusters {
+		index[c] = i
+	}
+	return func(weights map[string]int64) (float64, bool) {
+		var sum float64
+		for c, n := range weights {
+			if n == 0 {
+				continue
+			}
+			i, ok := index[c]
+			if !ok || n < 0 || int(n) > len(costs[i]) {
+				return 0, false
+			}
+			v := costs[i][n-1]
+			if math.IsInf(v, 0) || math.IsNaN(v) {
+				return 0, false
+			}
+			sum += v
+		}
+		return sum, true
+	}
+}
+
+// stabilizeRSPClusters reports whether the optimized cluster preferences should be applied to the RSP,
+// and if deferred by the stabilization window, the duration until the window elapses.
+//
+// The preferences are always applied if the weights are unchanged (only bounds may change), there is no record of the last applied weights,
+// or the candidate clusters or the total replicas have changed.
+// Otherwise, they are deferred within the window, and discarded if the improvement of the estimated cost is less than the threshold.
+func stabilizeRSPClusters(
+	st *v1beta1.RSPStabilization, last *rspAppliedWeights, lastTotalReplicas, totalReplicas int32, next *rspOptimizeResult, now time.Time,
+) (bool, time.Duration) {
+	if st == nil || last == nil {
+		return true, 0
+	}
+	weights := clusterWeights(next.clusters)
+	if sameWeights(last.weights, weights) {
+		return true, 0
+	}
+	if lastTotalReplicas != totalReplicas || !sameKeys(last.weights, weights) {
+		return true, 0
+	}
+	if st.Window != nil && st.Window.Duration > 0 {
+		if d := last.time.Add(st.Window.Duration).Sub(now); d > 0 {
+			return false, d
+		}
+	}
+	if st.MinImprovement != "" && next.cost != nil {
+		threshold, err := strconv.ParseFloat(st.MinImprovement, 64)
+		if err != nil {
+			return true, 0
+		}
+		lastCost, ok1 := next.cost(last.weights)
+		nextCost, ok2 := next.cost(weights)
+		if ok1 && ok2 && lastCost-nextCost < threshold {
+			return false, 0
+		}
+	}
+	return true, 0
+}
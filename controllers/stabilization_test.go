@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fedschedv1a1 "sigs.k8s.io/kubefed/pkg/apis/scheduling/v1alpha1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_getSetRSPAppliedWeights(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	annotations := map[string]string{}
+	if err := setRSPAppliedWeights(annotations, map[string]int64{"c1": 1, "c2": 2}, now); err != nil {
+		t.Fatal(err)
+	}
+	want := &rspAppliedWeights{weights: map[string]int64{"c1": 1, "c2": 2}, time: now}
+	got := getRSPAppliedWeights(annotations)
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(rspAppliedWeights{})); diff != "" {
+		t.Errorf("getRSPAppliedWeights() = %v, want %v, diff %s", got, want, diff)
+	}
+
+	for _, annotations := range []map[string]string{
+		nil,
+		{v1beta1.RSPLastAppliedWeightsAnnotation: `{"c1":1}`},
+		{v1beta1.RSPLastAppliedWeightsAnnotation: `foo`, v1beta1.RSPLastAppliedTimeAnnotation: "2023-01-02T03:04:05Z"},
+		{v1beta1.RSPLastAppliedWeightsAnnotation: `{"c1":1}`, v1beta1.RSPLastAppliedTimeAnnotation: "foo"},
+	} {
+		if got := getRSPAppliedWeights(annotations); got != nil {
+			t.Errorf("getRSPAppliedWeights(%v) = %v, want nil", annotations, got)
+		}
+	}
+}
+
+func Test_newPatternCostFunc(t *testing.T) {
+	cost := newPatternCostFunc([]string{"c1", "c2"}, [][]float64{{1, 3, 6}, {2, 4, math.Inf(1)}})
+	tests := []struct {
+		name    string
+		weights map[string]int64
+		want    float64
+		wantOK  bool
+	}{
+		{"empty", map[string]int64{}, 0, true},
+		{"zero", map[string]int64{"c1": 0, "c3": 0}, 0, true},
+		{"c1_c2", map[string]int64{"c1": 2, "c2": 1}, 5, true},
+		{"c1_only", map[string]int64{"c1": 3}, 6, true},
+		{"inf", map[string]int64{"c2": 3}, 0, false},
+		{"too_many", map[string]int64{"c1": 4}, 0, false},
+		{"unknown_cluster", map[string]int64{"c3": 1}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cost(tt.weights)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("cost() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_stabilizeRSPClusters(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	// c1 costs 1 per replica, c2 costs 2 per replica
+	cost := newPatternCostFunc([]string{"c1", "c2"}, [][]float64{{1, 2, 3, 4}, {2, 4, 6, 8}})
+	last := &rspAppliedWeights{weights: map[string]int64{"c1": 1, "c2": 3}, time: now.Add(-time.Minute)} // cost 7
+	next := &rspOptimizeResult{
+		clusters: map[string]fedschedv1a1.ClusterPreferences{"c1": {Weight: 3}, "c2": {Weight: 1}}, // cost 5
+		cost:     cost,
+	}
+	nextNoCost := &rspOptimizeResult{clusters: next.clusters}
+
+	tests := []struct {
+		name              string
+		st                *v1beta1.RSPStabilization
+		last              *rspAppliedWeights
+		lastTotalReplicas int32
+		next              *rspOptimizeResult
+		wantApply         bool
+		wantAfter         time.Duration
+	}{
+		{"no_stabilization", nil, last, 4, next, true, 0},
+		{"no_last", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: time.Hour}}, nil, 4, next, true, 0},
+		{"same_weights", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: time.Hour}},
+			&rspAppliedWeights{weights: map[string]int64{"c1": 3, "c2": 1}, time: now}, 4, next, true, 0},
+		{"replicas_changed", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: time.Hour}}, last, 5, next, true, 0},
+		{"clusters_changed", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: time.Hour}},
+			&rspAppliedWeights{weights: map[string]int64{"c1": 4}, time: now}, 4, next, true, 0},
+		{"within_window", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: 5 * time.Minute}}, last, 4, next, false, 4 * time.Minute},
+		{"window_elapsed", &v1beta1.RSPStabilization{Window: &metav1.Duration{Duration: 30 * time.Second}}, last, 4, next, true, 0},
+		{"improvement_enough", &v1beta1.RSPStabilization{MinImprovement: "2"}, last, 4, next, true, 0},
+		{"improvement_too_small", &v1beta1.RSPStabilization{MinImprovement: "2.5"}, last, 4, next, false, 0},
+		{"improvement_no_cost", &v1beta1.RSPStabilization{MinImprovement: "2.5"}, last, 4, nextNoCost, true, 0},
+		{"window_elapsed_improvement_too_small", &v1beta1.RSPStabilization{
+			Window: &metav1.Duration{Duration: 30 * time.Second}, MinImprovement: "2.5"}, last, 4, next, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotApply, gotAfter := stabilizeRSPClusters(tt.st, tt.last, tt.lastTotalReplicas, 4, tt.next, now)
+			if gotApply != tt.wantApply || gotAfter != tt.wantAfter {
+				t.Errorf("stabilizeRSPClusters() = %v, %v, want %v, %v", gotApply, gotAfter, tt.wantApply, tt.wantAfter)
+			}
+		})
+	}
+}
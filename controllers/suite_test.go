@@ -16,6 +16,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -116,6 +117,11 @@ var (
 		Version:  "v1beta1",
 		Resource: "federateddeployments",
 	}
+	federatedReplicaSetGVR = schema.GroupVersionResource{
+		Group:    "types.kubefed.io",
+		Version:  "v1beta1",
+		Resource: "federatedreplicasets",
+	}
 
 	testNS = "default"
 
@@ -199,6 +205,20 @@ var rspOptimizerBeforeEachFn = func() {
 		}).ShouldNot(Succeed())
 	}
 
+	// delete all FederatedReplicaSet
+	frsList, err := k8sDynamicClient.Resource(federatedReplicaSetGVR).Namespace(testNS).List(ctx, metav1.ListOptions{})
+	Expect(err).NotTo(HaveOccurred())
+	for _, frs := range frsList.Items {
+		err = k8sDynamicClient.Resource(federatedReplicaSetGVR).Namespace(frs.GetNamespace()).Delete(ctx, frs.GetName(), metav1.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+	for _, frs := range frsList.Items {
+		Eventually(func() error {
+			_, err = k8sDynamicClient.Resource(federatedReplicaSetGVR).Namespace(frs.GetNamespace()).Get(ctx, frs.GetName(), metav1.GetOptions{})
+			return err
+		}).ShouldNot(Succeed())
+	}
+
 	// delete all WAOFedConfig
 	err = k8sClient.DeleteAllOf(ctx, &v1beta1.WAOFedConfig{}, client.InNamespace("")) // cluster-scoped
 	Expect(err).NotTo(HaveOccurred())
@@ -396,6 +416,87 @@ var _ = Describe("RSPOptimizer controller", func() {
 		}).Should(Succeed())
 	})
 
+	It("should create and delete RSP when WAOFedConfig selector changed", func() {
+
+		wfc := testWFC11
+
+		ctx := context.Background()
+
+		// create WAOFedConfig
+		err := k8sClient.Create(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// create FederatedDeployment without annotations
+		fdeploy, _, _, err := helperLoadYAML(filepath.Join("testdata", "fdeploy2.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = k8sDynamicClient.Resource(federatedDeploymentGVR).Namespace(testNS).Create(ctx, fdeploy, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm RSP is NOT created
+		rsp := &fedschedv1a1.ReplicaSchedulingPreference{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fdeploy.GetNamespace(), Name: fdeploy.GetName()}, rsp)
+		}).ShouldNot(Succeed())
+
+		// update WAOFedConfig to select any FederatedDeployment
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)
+		Expect(err).NotTo(HaveOccurred())
+		wfc.Spec.Scheduling = testWFC12.Spec.Scheduling.DeepCopy()
+		err = k8sClient.Update(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm RSP is created
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fdeploy.GetNamespace(), Name: fdeploy.GetName()}, rsp)
+		}).Should(Succeed())
+
+		// update WAOFedConfig to select FederatedDeployments with the annotation
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)
+		Expect(err).NotTo(HaveOccurred())
+		wfc.Spec.Scheduling = testWFC11.Spec.Scheduling.DeepCopy()
+		err = k8sClient.Update(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm RSP is deleted
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fdeploy.GetNamespace(), Name: fdeploy.GetName()}, rsp)
+		}).ShouldNot(Succeed())
+	})
+
+	It("should create and delete RSP for FederatedReplicaSet", func() {
+
+		wfc := testWFC11
+
+		ctx := context.Background()
+
+		// create WAOFedConfig
+		err := k8sClient.Create(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// create FederatedReplicaSet
+		frs, _, _, err := helperLoadYAML(filepath.Join("testdata", "frs1.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = k8sDynamicClient.Resource(federatedReplicaSetGVR).Namespace(testNS).Create(ctx, frs, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm RSP is also created
+		rsp := &fedschedv1a1.ReplicaSchedulingPreference{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: frs.GetNamespace(), Name: frs.GetName()}, rsp)
+		}).Should(Succeed())
+		Expect(rsp.Spec.TargetKind).Should(Equal("FederatedReplicaSet"))
+		Expect(rsp.Spec.TotalReplicas).Should(Equal(int32(4)))
+
+		// delete FederatedReplicaSet
+		err = k8sDynamicClient.Resource(federatedReplicaSetGVR).Namespace(frs.GetNamespace()).Delete(ctx, frs.GetName(), metav1.DeleteOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm RSP is also deleted
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: frs.GetNamespace(), Name: frs.GetName()}, rsp)
+		}).ShouldNot(Succeed())
+	})
+
 	Context("schedule on clusters", func() {
 		wantX := map[string]fedschedv1a1.ClusterPreferences{}
 		_ = wantX
@@ -765,6 +866,53 @@ var _ = Describe("SLPOptimizer controller", func() {
 		}).Should(Succeed())
 	})
 
+	It("should create and delete SLP when WAOFedConfig selector changed", func() {
+
+		wfc := testWFC21
+
+		ctx := context.Background()
+
+		// create WAOFedConfig
+		err := k8sClient.Create(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// create FederatedService without annotations
+		fsvc, _, _, err := helperLoadYAML(filepath.Join("testdata", "fsvc2.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = k8sDynamicClient.Resource(federatedServiceGVR).Namespace(testNS).Create(ctx, fsvc, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm SLP is NOT created
+		slp := &v1beta1.ServiceLoadbalancingPreference{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fsvc.GetNamespace(), Name: fsvc.GetName()}, slp)
+		}).ShouldNot(Succeed())
+
+		// update WAOFedConfig to select any FederatedService
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)
+		Expect(err).NotTo(HaveOccurred())
+		wfc.Spec.LoadBalancing = testWFC22.Spec.LoadBalancing.DeepCopy()
+		err = k8sClient.Update(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm SLP is created
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fsvc.GetNamespace(), Name: fsvc.GetName()}, slp)
+		}).Should(Succeed())
+
+		// update WAOFedConfig to select FederatedServices with the annotation
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)
+		Expect(err).NotTo(HaveOccurred())
+		wfc.Spec.LoadBalancing = testWFC21.Spec.LoadBalancing.DeepCopy()
+		err = k8sClient.Update(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm SLP is deleted
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: fsvc.GetNamespace(), Name: fsvc.GetName()}, slp)
+		}).ShouldNot(Succeed())
+	})
+
 	Context("loadbalance on clusters", func() {
 		wantX := map[string]v1beta1.ClusterPreferences{}
 		_ = wantX
@@ -827,6 +975,15 @@ func testSLP(wfc v1beta1.WAOFedConfig, fsvcNS, fsvcFile string, want map[string]
 		return k8sClient.Get(ctx, client.ObjectKey{Namespace: fsvc.GetNamespace(), Name: fsvc.GetName()}, slp)
 	}).Should(Succeed())
 
+	// check SLP status
+	Eventually(func(g Gomega) {
+		g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(slp), slp)).To(Succeed())
+		g.Expect(slp.Status.Optimizer).NotTo(BeNil())
+		g.Expect(slp.Status.Optimizer.Method).To(Equal(*wfc.Spec.LoadBalancing.Optimizer.Method))
+		g.Expect(slp.Status.Optimizer.ObservedGeneration).To(Equal(slp.Generation))
+		g.Expect(slp.IsApplied()).To(BeFalse()) // no loadbalancer controller in tests
+	}).Should(Succeed())
+
 	// check SLP
 	if slp.Spec.Clusters == nil {
 		// both want == nil or want == map[string]v1beta1.ClusterPreferences{} are ok
@@ -835,3 +992,87 @@ func testSLP(wfc v1beta1.WAOFedConfig, fsvcNS, fsvcFile string, want map[string]
 		Expect(cmp.Diff(want, slp.Spec.Clusters)).Should(BeEmpty())
 	}
 }
+
+var waoFedConfigBeforeEachFn = func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cncl = cancel
+
+	// delete all WAOFedConfig
+	err := k8sClient.DeleteAllOf(ctx, &v1beta1.WAOFedConfig{}, client.InNamespace("")) // cluster-scoped
+	Expect(err).NotTo(HaveOccurred())
+	var wfc v1beta1.WAOFedConfig
+	Eventually(func() error {
+		return k8sClient.Get(ctx, client.ObjectKeyFromObject(&testWFC11), &wfc)
+	}).ShouldNot(Succeed())
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme.Scheme,
+	})
+	Expect(err).NotTo(HaveOccurred())
+
+	waoFedConfigReconciler := controllers.WAOFedConfigReconciler{
+		Client: k8sClient,
+		Scheme: scheme.Scheme,
+	}
+	err = waoFedConfigReconciler.SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			panic(err)
+		}
+	}()
+	wait()
+}
+
+var waoFedConfigAfterEachFn = func() {
+	cncl() // stop the mgr
+	wait()
+}
+
+var _ = Describe("WAOFedConfig controller", func() {
+
+	BeforeEach(waoFedConfigBeforeEachFn)
+	AfterEach(waoFedConfigAfterEachFn)
+
+	It("should be ready", func() {
+
+		wfc := testWFC11
+
+		ctx := context.Background()
+
+		// create WAOFedConfig
+		err := k8sClient.Create(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm status is updated
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)).To(Succeed())
+			g.Expect(wfc.Status.ObservedGeneration).To(Equal(wfc.Generation))
+			g.Expect(meta.IsStatusConditionTrue(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionKubeFedNamespaceFound)).To(BeTrue())
+			g.Expect(meta.IsStatusConditionTrue(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionEstimatorsReachable)).To(BeTrue())
+			g.Expect(meta.IsStatusConditionTrue(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionReady)).To(BeTrue())
+			g.Expect(wfc.Status.Clusters).NotTo(BeEmpty())
+		}).Should(Succeed())
+	})
+
+	It("should not be ready as KubeFed namespace not found", func() {
+
+		wfc := testWFC11
+		wfc.Spec.KubeFedNamespace = "waofed-not-found"
+
+		ctx := context.Background()
+
+		// create WAOFedConfig
+		err := k8sClient.Create(ctx, &wfc)
+		Expect(err).NotTo(HaveOccurred())
+
+		// confirm status is updated
+		Eventually(func(g Gomega) {
+			g.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(&wfc), &wfc)).To(Succeed())
+			g.Expect(meta.IsStatusConditionFalse(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionKubeFedNamespaceFound)).To(BeTrue())
+			g.Expect(meta.IsStatusConditionFalse(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionReady)).To(BeTrue())
+			g.Expect(wfc.Status.Clusters).To(BeEmpty())
+		}).Should(Succeed())
+	})
+})
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Nedopro2022/wao-estimator/pkg/estimator"
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+const (
+	// waoEstimatorProbeTimeout is the timeout for a WAO-Estimator health probe.
+	waoEstimatorProbeTimeout = 5 * time.Second
+	// waoEstimatorProbeCPUMilli is the CPU requests of the workload used for WAO-Estimator health probes.
+	waoEstimatorProbeCPUMilli = 100
+)
+
+// probeWAOEstimator checks whether the WAO-Estimator responds to a minimal request.
+func probeWAOEstimator(ctx context.Context, c client.Reader, conf *v1beta1.WAOEstimatorSetting) error {
+	ctx, cancel := context.WithTimeout(ctx, waoEstimatorProbeTimeout)
+	defer cancel()
+
+	// NOTE: probes are rate limited as well as estimations, but are not cached, retried nor blocked by the circuit breaker
+	_, err := defaultWAOEstimatorPool.send(ctx, c, conf, waoEstimatorProbeCPUMilli, 1)
+	return err
+}
+
+// waoWorkloads returns the CPU requests per replica sent to WAO-Estimators and the total replicas of the given FederatedDeployments.
+// Pods without CPU requests or limits are counted with the non-zero requests (Ref. podResources).
+//
+// NOTE: the WAO-Estimator API only accepts CPU, so the other resources (e.g. memory) are logged but not sent.
+func waoWorkloads(ctx context.Context, fdeploys []*structuredFederatedDeployment) (cpuMilli, replicas int) {
+	lg := log.FromContext(ctx)
+
+	requests, replicas := aggregateWorkloads(fdeploys, true)
+	lg.Info("workload resources", "requests", requests, "replicas", replicas)
+	return int(requests.Cpu().MilliValue()), replicas
+}
+
+// computeLeastCostWeightsWAO calls WAO-Estimators of the given clusters to get estimated power increases
+// and returns the number of workloads to be allocated on each cluster that minimize the total power increase.
+// The returned slice has the same order as the given clusters.
+//
+// bounds is optional, and bounds[i] limits the number of workloads allocated on clusters[i].
+func computeLeastCostWeightsWAO(
+	ctx context.Context, c client.Reader, clusters []string, estimators map[string]*v1beta1.WAOEstimatorSetting, cpuMilli, replicas int, bounds []v1beta1.ReplicaBounds,
+) ([]int, error) {
+	lg := log.FromContext(ctx)
+
+	estimatedCosts := estimatePowerIncreasesWAO(ctx, c, clusters, estimators, cpuMilli, replicas)
+
+	lg.Info("call ComputeLeastCostPatternsFn", "clusters", clusters, "costs", estimatedCosts, "bounds", bounds)
+
+	minCost, pattern, err := computeLeastCostPatternWithBounds(estimatedCosts, replicas, bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	lg.Info("called ComputeLeastCostPatternsFn", "minCost", minCost, "clusters", clusters, "pattern", pattern)
+
+	return pattern, nil
+}
+
+// estimatePowerIncreasesWAO calls WAO-Estimators of the given clusters in parallel and returns the estimated power increases,
+// where the result[i][n-1] is the power increase of allocating n workloads on clusters[i].
+// The power increases of the clusters whose WAO-Estimators fail are +Inf.
+//
+// Requests are sent through the shared pool, so they may be coalesced, cached and rate limited.
+// The returned slices must not be modified.
+func estimatePowerIncreasesWAO(ctx context.Context, c client.Reader, clusters []string, estimators map[string]*v1beta1.WAOEstimatorSetting, cpuMilli, replicas int) [][]float64 {
+	lg := log.FromContext(ctx)
+
+	estimatedCosts := make([][]float64, len(clusters))
+
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		i := i
+		cluster := cluster
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			costs, err := defaultWAOEstimatorPool.estimatePowerIncreases(ctx, c, cluster, estimators[cluster], cpuMilli, replicas)
+			if err != nil {
+				lg.Error(err, "EstimatePowerConsumption", "cluster", cluster)
+				costs = make([]float64, replicas)
+				for i := range costs {
+					costs[i] = math.Inf(1)
+				}
+			}
+			estimatedCosts[i] = costs
+		}()
+	}
+	wg.Wait()
+
+	return estimatedCosts
+}
+
+// computeLeastCostPatternWithBounds returns the least cost pattern of allocating the given number of workloads
+// within the given bounds. costs[i][n-1] is the cost of allocating n workloads on the i-th cluster.
+//
+// MinReplicas are allocated first, then the remaining workloads are allocated by estimator.ComputeLeastCostPatternsFn
+// with the marginal costs, where allocations exceeding MaxReplicas cost +Inf.
+// If the sum of MinReplicas is not less than replicas, MinReplicas are returned as is.
+// It returns an error if every pattern costs +Inf.
+func computeLeastCostPatternWithBounds(costs [][]float64, replicas int, bounds []v1beta1.ReplicaBounds) (float64, []int, error) {
+	mins := make([]int, len(costs))
+	remaining := replicas
+	for i := range bounds {
+		mins[i] = int(bounds[i].MinReplicas)
+		remaining -= mins[i]
+	}
+	if len(bounds) > 0 && remaining <= 0 {
+		return 0, mins, nil
+	}
+
+	marginalCosts := make([][]float64, len(costs))
+	for i := range costs {
+		marginalCosts[i] = make([]float64, remaining)
+		base := 0.0
+		if mins[i] > 0 {
+			base = costs[i][mins[i]-1]
+		}
+		for k := 1; k <= remaining; k++ {
+			n := mins[i] + k
+			c := costs[i][n-1]
+			switch {
+			case i < len(bounds) && bounds[i].MaxReplicas != nil && int64(n) > *bounds[i].MaxReplicas:
+				c = math.Inf(1)
+			case math.IsInf(c, 1) || math.IsInf(base, 1):
+				c = math.Inf(1)
+			default:
+				c -= base
+			}
+			marginalCosts[i][k-1] = c
+		}
+	}
+
+	minCost, minCostPatterns, err := estimator.ComputeLeastCostPatternsFn(len(costs), remaining, marginalCosts)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(minCostPatterns) == 0 {
+		return 0, nil, fmt.Errorf("no patterns found")
+	}
+	if math.IsInf(minCost, 1) {
+		// e.g. all WAO-Estimators failed, or the sum of MaxReplicas is less than replicas
+		return 0, nil, fmt.Errorf("no feasible patterns found")
+	}
+
+	// NOTE: use the first pattern at this time
+	pattern := make([]int, len(costs))
+	for i := range pattern {
+		pattern[i] = mins[i] + minCostPatterns[0][i]
+	}
+	return minCost, pattern, nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/pointer"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_computeLeastCostPatternWithBounds(t *testing.T) {
+	inf := math.Inf(1)
+	// c1 is cheaper than c2 for any number of workloads
+	costs := [][]float64{
+		{10, 20, 30, 40},
+		{100, 200, 300, 400},
+	}
+	tests := []struct {
+		name     string
+		costs    [][]float64
+		replicas int
+		bounds   []v1beta1.ReplicaBounds
+		want     []int
+		wantErr  bool
+	}{
+		{"no_bounds", costs, 4, nil, []int{4, 0}, false},
+		{"unbounded", costs, 4, []v1beta1.ReplicaBounds{{}, {}}, []int{4, 0}, false},
+		{"min", costs, 4, []v1beta1.ReplicaBounds{{}, {MinReplicas: 1}}, []int{3, 1}, false},
+		{"max", costs, 4, []v1beta1.ReplicaBounds{{MaxReplicas: pointer.Int64(1)}, {}}, []int{1, 3}, false},
+		{"min_and_max", costs, 4, []v1beta1.ReplicaBounds{{MaxReplicas: pointer.Int64(2)}, {MinReplicas: 1}}, []int{2, 2}, false},
+		{"min_exceeds_replicas", costs, 4, []v1beta1.ReplicaBounds{{MinReplicas: 3}, {MinReplicas: 3}}, []int{3, 3}, false},
+		{"marginal", [][]float64{
+			{10, 100, 200, 300},
+			{50, 60, 70, 80},
+		}, 4, []v1beta1.ReplicaBounds{{MinReplicas: 2}, {}}, []int{2, 2}, false},
+		{"unavailable", [][]float64{
+			{inf, inf, inf, inf},
+			{100, 200, 300, 400},
+		}, 4, []v1beta1.ReplicaBounds{{MinReplicas: 1}, {}}, []int{1, 3}, false},
+		{"zero_replicas", [][]float64{{}, {}}, 0, nil, []int{0, 0}, false},
+		{"all_unavailable", [][]float64{
+			{inf, inf, inf, inf},
+			{inf, inf, inf, inf},
+		}, 4, nil, nil, true},
+		{"max_too_small", costs, 4, []v1beta1.ReplicaBounds{{MaxReplicas: pointer.Int64(1)}, {MaxReplicas: pointer.Int64(1)}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := computeLeastCostPatternWithBounds(tt.costs, tt.replicas, tt.bounds)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("computeLeastCostPatternWithBounds() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if diff := cmp.Diff(got, tt.want); diff != "" {
+				t.Errorf("computeLeastCostPatternWithBounds() = %v, want %v, diff %s", got, tt.want, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// waoFedConfigResyncPeriod is the interval to refresh WAOFedConfig status (e.g. WAO-Estimator health).
+const waoFedConfigResyncPeriod = 1 * time.Minute
+
+// WAOFedConfigReconciler reconciles a WAOFedConfig object
+type WAOFedConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	ControllerName string
+}
+
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=core.kubefed.io,resources=kubefedclusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=waofedconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=waofed.bitmedia.co.jp,resources=waofedconfigs/status,verbs=get;update;patch
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *WAOFedConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.ControllerName = v1beta1.OperatorName + "-waofedconfig-controller"
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// status updates by this controller must not trigger reconciliation
+		For(&v1beta1.WAOFedConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&source.Kind{Type: &fedcorev1b1.KubeFedCluster{}},
+			handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: v1beta1.WAOFedConfigName}}}
+			}),
+			builder.WithPredicates(kubeFedClusterPredicate),
+		).
+		Complete(r)
+}
+
+// Reconcile moves the current state of the cluster closer to the desired state.
+func (r *WAOFedConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	lg := log.FromContext(ctx)
+	lg.Info("Reconcile")
+
+	// get WAOFedConfig
+	wfc := &v1beta1.WAOFedConfig{}
+	err := r.Get(ctx, req.NamespacedName, wfc)
+	if errors.IsNotFound(err) {
+		lg.Info("WAOFedConfig is already deleted")
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		lg.Error(err, "unable to get WAOFedConfig")
+		return ctrl.Result{}, err
+	}
+
+	obs, err := r.observe(ctx, wfc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	wfc.Status = newWAOFedConfigStatus(wfc, obs, metav1.Now())
+	if err := r.Status().Update(ctx, wfc); err != nil {
+		lg.Error(err, "unable to update WAOFedConfig status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: waoFedConfigResyncPeriod}, nil
+}
+
+// waoFedConfigObservation holds the observed state used to compute WAOFedConfig status.
+type waoFedConfigObservation struct {
+	kubeFedNamespaceFound bool
+	clusters              []fedcorev1b1.KubeFedCluster
+	// schedulingEstimators and loadBalancingEstimators map cluster names to WAO-Estimator probe results,
+	// only available if the "wao" method is used.
+	schedulingEstimators    map[string]*v1beta1.EstimatorStatus
+	loadBalancingEstimators map[string]*v1beta1.EstimatorStatus
+}
+
+func (r *WAOFedConfigReconciler) observe(ctx context.Context, wfc *v1beta1.WAOFedConfig) (*waoFedConfigObservation, error) {
+	lg := log.FromContext(ctx)
+
+	obs := &waoFedConfigObservation{}
+
+	// check KubeFed namespace
+	ns := &corev1.Namespace{}
+	err := r.Get(ctx, client.ObjectKey{Name: wfc.Spec.KubeFedNamespace}, ns)
+	switch {
+	case errors.IsNotFound(err):
+		obs.kubeFedNamespaceFound = false
+	case err != nil:
+		lg.Error(err, "unable to get KubeFed namespace")
+		return nil, err
+	default:
+		obs.kubeFedNamespaceFound = true
+	}
+
+	// discover clusters
+	if obs.kubeFedNamespaceFound {
+		cl := &fedcorev1b1.KubeFedClusterList{}
+		if err := r.List(ctx, cl, &client.ListOptions{Namespace: wfc.Spec.KubeFedNamespace}); err != nil {
+			lg.Error(err, "unable to list KubeFedClusters")
+			return nil, err
+		}
+		obs.clusters = cl.Items
+		sort.Slice(obs.clusters, func(i, j int) bool { return obs.clusters[i].Name < obs.clusters[j].Name })
+	}
+
+	// probe WAO-Estimators
+	if s := wfc.Spec.Scheduling; s != nil && rspOptimizerUsesWAOEstimators(s.Optimizer) {
+		estimators := discoverWAOEstimators(ctx, wfc.Spec.KubeFedNamespace, obs.clusters, s.Optimizer.WAOEstimators, s.Optimizer.WAOEstimatorDiscovery)
+		obs.schedulingEstimators = probeWAOEstimators(ctx, r.Client, obs.clusters, estimators)
+	}
+	if s := wfc.Spec.LoadBalancing; s != nil && slpOptimizerUsesWAOEstimators(s.Optimizer) {
+		estimators := discoverWAOEstimators(ctx, wfc.Spec.KubeFedNamespace, obs.clusters, s.Optimizer.WAOEstimators, s.Optimizer.WAOEstimatorDiscovery)
+		obs.loadBalancingEstimators = probeWAOEstimators(ctx, r.Client, obs.clusters, estimators)
+	}
+
+	return obs, nil
+}
+
+// probeWAOEstimators probes WAO-Estimators of the given clusters concurrently.
+func probeWAOEstimators(ctx context.Context, r client.Reader, clusters []fedcorev1b1.KubeFedCluster, estimators map[string]*v1beta1.WAOEstimatorSetting) map[string]*v1beta1.EstimatorStatus {
+	lg := log.FromContext(ctx)
+
+	var mu sync.Mutex
+	m := make(map[string]*v1beta1.EstimatorStatus, len(clusters))
+
+	var wg sync.WaitGroup
+	for _, c := range clusters {
+		name := c.Name
+		conf, ok := estimators[name]
+		if !ok || conf == nil {
+			mu.Lock()
+			m[name] = &v1beta1.EstimatorStatus{Reachable: false, Message: "no WAO-Estimator configured", LastProbeTime: metav1.Now()}
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			es := &v1beta1.EstimatorStatus{Endpoint: conf.Endpoint, Reachable: true, CircuitOpen: defaultWAOEstimatorPool.circuitOpen(conf)}
+			if err := probeWAOEstimator(ctx, r, conf); err != nil {
+				lg.Info("WAO-Estimator is not reachable", "cluster", name, "endpoint", conf.Endpoint, "err", err)
+				es.Reachable = false
+				es.Message = err.Error()
+			}
+			es.LastProbeTime = metav1.Now()
+			mu.Lock()
+			m[name] = es
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return m
+}
+
+// newWAOFedConfigStatus computes WAOFedConfig status from the observation.
+// Conditions in the current status are kept so that lastTransitionTime only changes on transitions.
+func newWAOFedConfigStatus(wfc *v1beta1.WAOFedConfig, obs *waoFedConfigObservation, now metav1.Time) v1beta1.WAOFedConfigStatus {
+	status := v1beta1.WAOFedConfigStatus{
+		ObservedGeneration: wfc.Generation,
+		Conditions:         append([]metav1.Condition{}, wfc.Status.Conditions...),
+	}
+
+	// clusters
+	for _, c := range obs.clusters {
+		status.Clusters = append(status.Clusters, v1beta1.ClusterStatus{
+			Name:                   c.Name,
+			Ready:                  isKubeFedClusterReady(&c),
+			SchedulingEstimator:    obs.schedulingEstimators[c.Name],
+			LoadBalancingEstimator: obs.loadBalancingEstimators[c.Name],
+		})
+	}
+
+	setCondition := func(condType string, ok bool, reason, message string) {
+		cond := metav1.Condition{
+			Type:               condType,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wfc.Generation,
+			LastTransitionTime: now,
+			Reason:             reason,
+			Message:            message,
+		}
+		if ok {
+			cond.Status = metav1.ConditionTrue
+		}
+		meta.SetStatusCondition(&status.Conditions, cond)
+	}
+
+	// KubeFedNamespaceFound
+	if obs.kubeFedNamespaceFound {
+		setCondition(v1beta1.WAOFedConfigConditionKubeFedNamespaceFound, true, "Found",
+			fmt.Sprintf("namespace %s found with %d KubeFedClusters", wfc.Spec.KubeFedNamespace, len(obs.clusters)))
+	} else {
+		setCondition(v1beta1.WAOFedConfigConditionKubeFedNamespaceFound, false, "NotFound",
+			fmt.Sprintf("namespace %s not found", wfc.Spec.KubeFedNamespace))
+	}
+
+	// EstimatorsReachable
+	// NOTE: WAO-Estimators with open circuits are not used by the optimizers even if they respond to the probes
+	var unreachable []string
+	for _, c := range status.Clusters {
+		for i, es := range []*v1beta1.EstimatorStatus{c.SchedulingEstimator, c.LoadBalancingEstimator} {
+			name := []string{"scheduling/", "loadbalancing/"}[i] + c.Name
+			switch {
+			case es == nil:
+			case es.CircuitOpen:
+				unreachable = append(unreachable, name+" (circuit open)")
+			case !es.Reachable:
+				unreachable = append(unreachable, name)
+			}
+		}
+	}
+	switch {
+	case obs.schedulingEstimators == nil && obs.loadBalancingEstimators == nil:
+		setCondition(v1beta1.WAOFedConfigConditionEstimatorsReachable, true, "NotRequired", "no method uses WAO-Estimators")
+	case len(unreachable) == 0:
+		setCondition(v1beta1.WAOFedConfigConditionEstimatorsReachable, true, "Reachable", "all WAO-Estimators are reachable")
+	default:
+		setCondition(v1beta1.WAOFedConfigConditionEstimatorsReachable, false, "Unreachable",
+			fmt.Sprintf("WAO-Estimators not reachable: %s", strings.Join(unreachable, ", ")))
+	}
+
+	// Ready
+	var notReady []string
+	for _, t := range []string{v1beta1.WAOFedConfigConditionKubeFedNamespaceFound, v1beta1.WAOFedConfigConditionEstimatorsReachable} {
+		if !meta.IsStatusConditionTrue(status.Conditions, t) {
+			notReady = append(notReady, t)
+		}
+	}
+	if len(notReady) == 0 {
+		setCondition(v1beta1.WAOFedConfigConditionReady, true, "Ready", "WAOFedConfig is ready")
+	} else {
+		setCondition(v1beta1.WAOFedConfigConditionReady, false, "NotReady",
+			fmt.Sprintf("conditions not satisfied: %s", strings.Join(notReady, ", ")))
+	}
+
+	return status
+}
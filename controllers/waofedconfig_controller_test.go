@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func Test_newWAOFedConfigStatus(t *testing.T) {
+	ready := fedcorev1b1.ClusterCondition{Type: common.ClusterReady, Status: corev1.ConditionTrue}
+	notReady := fedcorev1b1.ClusterCondition{Type: common.ClusterReady, Status: corev1.ConditionFalse}
+	c1 := *helperKubeFedCluster(nil, 1, ready)
+	c2 := *helperKubeFedCluster(nil, 1, notReady)
+	c2.Name = "c2"
+
+	reachable := &v1beta1.EstimatorStatus{Endpoint: "http://c1", Reachable: true}
+	unreachable := &v1beta1.EstimatorStatus{Endpoint: "http://c2", Reachable: false, Message: "connection refused"}
+	circuitOpen := &v1beta1.EstimatorStatus{Endpoint: "http://c1", Reachable: true, CircuitOpen: true}
+
+	tests := []struct {
+		name         string
+		obs          *waoFedConfigObservation
+		wantConds    map[string]metav1.ConditionStatus
+		wantClusters []v1beta1.ClusterStatus
+	}{
+		{"ns_not_found", &waoFedConfigObservation{}, map[string]metav1.ConditionStatus{
+			v1beta1.WAOFedConfigConditionKubeFedNamespaceFound: metav1.ConditionFalse,
+			v1beta1.WAOFedConfigConditionEstimatorsReachable:   metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionReady:                 metav1.ConditionFalse,
+		}, nil},
+		{"no_estimators", &waoFedConfigObservation{
+			kubeFedNamespaceFound: true,
+			clusters:              []fedcorev1b1.KubeFedCluster{c1, c2},
+		}, map[string]metav1.ConditionStatus{
+			v1beta1.WAOFedConfigConditionKubeFedNamespaceFound: metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionEstimatorsReachable:   metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionReady:                 metav1.ConditionTrue,
+		}, []v1beta1.ClusterStatus{
+			{Name: "c1", Ready: true},
+			{Name: "c2", Ready: false},
+		}},
+		{"estimators_reachable", &waoFedConfigObservation{
+			kubeFedNamespaceFound: true,
+			clusters:              []fedcorev1b1.KubeFedCluster{c1},
+			schedulingEstimators:  map[string]*v1beta1.EstimatorStatus{"c1": reachable},
+		}, map[string]metav1.ConditionStatus{
+			v1beta1.WAOFedConfigConditionKubeFedNamespaceFound: metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionEstimatorsReachable:   metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionReady:                 metav1.ConditionTrue,
+		}, []v1beta1.ClusterStatus{
+			{Name: "c1", Ready: true, SchedulingEstimator: reachable},
+		}},
+		{"estimators_unreachable", &waoFedConfigObservation{
+			kubeFedNamespaceFound:   true,
+			clusters:                []fedcorev1b1.KubeFedCluster{c1, c2},
+			schedulingEstimators:    map[string]*v1beta1.EstimatorStatus{"c1": reachable, "c2": reachable},
+			loadBalancingEstimators: map[string]*v1beta1.EstimatorStatus{"c1": reachable, "c2": unreachable},
+		}, map[string]metav1.ConditionStatus{
+			v1beta1.WAOFedConfigConditionKubeFedNamespaceFound: metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionEstimatorsReachable:   metav1.ConditionFalse,
+			v1beta1.WAOFedConfigConditionReady:                 metav1.ConditionFalse,
+		}, []v1beta1.ClusterStatus{
+			{Name: "c1", Ready: true, SchedulingEstimator: reachable, LoadBalancingEstimator: reachable},
+			{Name: "c2", Ready: false, SchedulingEstimator: reachable, LoadBalancingEstimator: unreachable},
+		}},
+		{"estimators_circuit_open", &waoFedConfigObservation{
+			kubeFedNamespaceFound: true,
+			clusters:              []fedcorev1b1.KubeFedCluster{c1},
+			schedulingEstimators:  map[string]*v1beta1.EstimatorStatus{"c1": circuitOpen},
+		}, map[string]metav1.ConditionStatus{
+			v1beta1.WAOFedConfigConditionKubeFedNamespaceFound: metav1.ConditionTrue,
+			v1beta1.WAOFedConfigConditionEstimatorsReachable:   metav1.ConditionFalse,
+			v1beta1.WAOFedConfigConditionReady:                 metav1.ConditionFalse,
+		}, []v1beta1.ClusterStatus{
+			{Name: "c1", Ready: true, SchedulingEstimator: circuitOpen},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wfc := &v1beta1.WAOFedConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: v1beta1.WAOFedConfigName, Generation: 2},
+				Spec:       v1beta1.WAOFedConfigSpec{KubeFedNamespace: "kube-federation-system"},
+			}
+			got := newWAOFedConfigStatus(wfc, tt.obs, metav1.Unix(1, 0))
+			if got.ObservedGeneration != 2 {
+				t.Errorf("newWAOFedConfigStatus().ObservedGeneration = %v, want %v", got.ObservedGeneration, 2)
+			}
+			gotConds := map[string]metav1.ConditionStatus{}
+			for _, c := range got.Conditions {
+				gotConds[c.Type] = c.Status
+			}
+			if diff := cmp.Diff(gotConds, tt.wantConds); diff != "" {
+				t.Errorf("newWAOFedConfigStatus().Conditions = %v, want %v, diff %s", gotConds, tt.wantConds, diff)
+			}
+			if diff := cmp.Diff(got.Clusters, tt.wantClusters); diff != "" {
+				t.Errorf("newWAOFedConfigStatus().Clusters = %v, want %v, diff %s", got.Clusters, tt.wantClusters, diff)
+			}
+		})
+	}
+}
+
+func Test_newWAOFedConfigStatus_lastTransitionTime(t *testing.T) {
+	wfc := &v1beta1.WAOFedConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1beta1.WAOFedConfigName},
+		Spec:       v1beta1.WAOFedConfigSpec{KubeFedNamespace: "kube-federation-system"},
+	}
+	obs := &waoFedConfigObservation{kubeFedNamespaceFound: true}
+	t1, t2, t3 := metav1.Unix(1, 0), metav1.Unix(2, 0), metav1.Unix(3, 0)
+
+	wfc.Status = newWAOFedConfigStatus(wfc, obs, t1)
+
+	// unchanged
+	wfc.Status = newWAOFedConfigStatus(wfc, obs, t2)
+	if got := meta.FindStatusCondition(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionReady).LastTransitionTime; !got.Equal(&t1) {
+		t.Errorf("lastTransitionTime = %v, want %v", got, t1)
+	}
+
+	// changed
+	obs.kubeFedNamespaceFound = false
+	wfc.Status = newWAOFedConfigStatus(wfc, obs, t3)
+	if got := meta.FindStatusCondition(wfc.Status.Conditions, v1beta1.WAOFedConfigConditionReady).LastTransitionTime; !got.Equal(&t3) {
+		t.Errorf("lastTransitionTime = %v, want %v", got, t3)
+	}
+}
+
+func Test_probeWAOEstimators(t *testing.T) {
+	srv, _ := helperWAOEstimatorServer(t, 10*time.Millisecond, nil)
+	var clusters []fedcorev1b1.KubeFedCluster
+	for _, name := range []string{"c1", "c2", "c3", "c4", "c5", "c6"} {
+		clusters = append(clusters, fedcorev1b1.KubeFedCluster{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+	// the clusters with and without WAO-Estimators are interleaved so that the map is written while probes are running
+	estimators := map[string]*v1beta1.WAOEstimatorSetting{
+		"c1": {Endpoint: srv.URL, Namespace: "default", Name: "default"},
+		"c3": {Endpoint: srv.URL, Namespace: "default", Name: "default"},
+		"c5": {Endpoint: srv.URL, Namespace: "default", Name: "notfound"},
+	}
+	unconfigured := &v1beta1.EstimatorStatus{Reachable: false, Message: "no WAO-Estimator configured"}
+	want := map[string]*v1beta1.EstimatorStatus{
+		"c1": {Endpoint: srv.URL, Reachable: true},
+		"c2": unconfigured,
+		"c3": {Endpoint: srv.URL, Reachable: true},
+		"c4": unconfigured,
+		"c5": {Endpoint: srv.URL, Reachable: false},
+		"c6": unconfigured,
+	}
+
+	got := probeWAOEstimators(context.Background(), fake.NewClientBuilder().Build(), clusters, estimators)
+	if es := got["c5"]; es == nil || es.Message == "" {
+		t.Fatalf("probeWAOEstimators()[c5] = %v, want an error message", es)
+	}
+	want["c5"].Message = got["c5"].Message
+	if diff := cmp.Diff(got, want, cmpopts.IgnoreFields(v1beta1.EstimatorStatus{}, "LastProbeTime")); diff != "" {
+		t.Errorf("probeWAOEstimators() = %v, want %v, diff %s", got, want, diff)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	fedctrlutil "sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+// getWAOFedConfig gets the only instance of WAOFedConfig.
+func getWAOFedConfig(ctx context.Context, c client.Reader) (*v1beta1.WAOFedConfig, error) {
+	wfc := &v1beta1.WAOFedConfig{}
+	wfc.Name = v1beta1.WAOFedConfigName
+	if err := c.Get(ctx, client.ObjectKeyFromObject(wfc), wfc); err != nil {
+		return nil, err
+	}
+	return wfc, nil
+}
+
+// listFederatedObjects lists all federated objects of the given GVK (in all namespaces unless opts specify).
+func listFederatedObjects(ctx context.Context, c client.Reader, gvk schema.GroupVersionKind, opts ...client.ListOption) ([]unstructured.Unstructured, error) {
+	ul := &unstructured.UnstructuredList{}
+	ul.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := c.List(ctx, ul, opts...); err != nil {
+		return nil, err
+	}
+	return ul.Items, nil
+}
+
+// waoFedConfigPredicate filters out WAOFedConfig updates that never change optimization results (e.g. status updates).
+var waoFedConfigPredicate = predicate.GenerationChangedPredicate{}
+
+// namespacePredicate filters out Namespace updates that never change ResourceSelector results.
+var namespacePredicate = predicate.LabelChangedPredicate{}
+
+// kubeFedClusterPredicate filters out KubeFedCluster updates that never change optimization results.
+//
+// KubeFed updates status.conditions[*].lastProbeTime periodically,
+// so only label and annotation changes (e.g. WAO-Estimator endpoints for waoEstimatorDiscovery) and condition type/status changes are considered.
+var kubeFedClusterPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldCluster, ok := e.ObjectOld.(*fedcorev1b1.KubeFedCluster)
+		if !ok {
+			return true
+		}
+		newCluster, ok := e.ObjectNew.(*fedcorev1b1.KubeFedCluster)
+		if !ok {
+			return true
+		}
+		if !reflect.DeepEqual(oldCluster.Labels, newCluster.Labels) || !reflect.DeepEqual(oldCluster.Annotations, newCluster.Annotations) {
+			return true
+		}
+		return !reflect.DeepEqual(kubeFedClusterConditionStatuses(oldCluster), kubeFedClusterConditionStatuses(newCluster))
+	},
+}
+
+func kubeFedClusterConditionStatuses(c *fedcorev1b1.KubeFedCluster) map[string]string {
+	m := make(map[string]string, len(c.Status.Conditions))
+	for _, cond := range c.Status.Conditions {
+		m[string(cond.Type)] = string(cond.Status)
+	}
+	return m
+}
+
+// isKubeFedClusterReady reports whether the KubeFedCluster has the Ready condition with status True.
+func isKubeFedClusterReady(c *fedcorev1b1.KubeFedCluster) bool {
+	for _, cond := range c.Status.Conditions {
+		if cond.Type == common.ClusterReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// placementMayInclude reports whether the placement may select the cluster.
+//
+// placement.clusterSelector is always regarded as including the cluster,
+// as the cluster labels before the change are unknown.
+func placementMayInclude(placement *fedctrlutil.GenericPlacementFields, cluster string) bool {
+	// NOTE: keep consistent with optimizeClusterWeights (pass the placement through placementWithDefault for RSPOptimizer)
+	if placement == nil {
+		return false
+	}
+	if placement.Clusters != nil {
+		for _, c := range placement.Clusters {
+			if c.Name == cluster {
+				return true
+			}
+		}
+		return false
+	}
+	return placement.ClusterSelector != nil
+}
+
+func toRequests(m map[types.NamespacedName]struct{}) []reconcile.Request {
+	reqs := make([]reconcile.Request, 0, len(m))
+	for k := range m {
+		reqs = append(reqs, reconcile.Request{NamespacedName: k})
+	}
+	return reqs
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/kubefed/pkg/apis/core/common"
+	fedcorev1b1 "sigs.k8s.io/kubefed/pkg/apis/core/v1beta1"
+	"sigs.k8s.io/kubefed/pkg/controller/util"
+
+	v1beta1 "github.com/Nedopro2022/waofed/api/v1beta1"
+)
+
+func helperKubeFedCluster(labels map[string]string, probe int64, conds ...fedcorev1b1.ClusterCondition) *fedcorev1b1.KubeFedCluster {
+	c := &fedcorev1b1.KubeFedCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "c1", Namespace: "kube-federation-system", Labels: labels},
+	}
+	for _, cond := range conds {
+		cond.LastProbeTime = metav1.Unix(probe, 0)
+		c.Status.Conditions = append(c.Status.Conditions, cond)
+	}
+	return c
+}
+
+func Test_kubeFedClusterPredicate(t *testing.T) {
+	ready := fedcorev1b1.ClusterCondition{Type: common.ClusterReady, Status: corev1.ConditionTrue}
+	notReady := fedcorev1b1.ClusterCondition{Type: common.ClusterReady, Status: corev1.ConditionFalse}
+	offline := fedcorev1b1.ClusterCondition{Type: common.ClusterOffline, Status: corev1.ConditionTrue}
+	tests := []struct {
+		name string
+		old  *fedcorev1b1.KubeFedCluster
+		new  *fedcorev1b1.KubeFedCluster
+		want bool
+	}{
+		{"probe_only", helperKubeFedCluster(nil, 1, ready), helperKubeFedCluster(nil, 2, ready), false},
+		{"not_ready", helperKubeFedCluster(nil, 1, ready), helperKubeFedCluster(nil, 2, notReady), true},
+		{"offline", helperKubeFedCluster(nil, 1, notReady), helperKubeFedCluster(nil, 2, notReady, offline), true},
+		{"first_probe", helperKubeFedCluster(nil, 1), helperKubeFedCluster(nil, 2, ready), true},
+		{"labels_added", helperKubeFedCluster(nil, 1, ready), helperKubeFedCluster(map[string]string{"a": "b"}, 1, ready), true},
+		{"labels_changed", helperKubeFedCluster(map[string]string{"a": "b"}, 1, ready), helperKubeFedCluster(map[string]string{"a": "c"}, 1, ready), true},
+		{"annotations_changed", helperKubeFedCluster(nil, 1, ready), func() *fedcorev1b1.KubeFedCluster {
+			c := helperKubeFedCluster(nil, 2, ready)
+			c.Annotations = map[string]string{v1beta1.WAOEstimatorEndpointAnnotation: "http://localhost:5657"}
+			return c
+		}(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kubeFedClusterPredicate.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("kubeFedClusterPredicate.Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_placementMayInclude(t *testing.T) {
+	tests := []struct {
+		name      string
+		placement *util.GenericPlacementFields
+		want      bool
+	}{
+		{"nil", nil, false},
+		{"empty", &util.GenericPlacementFields{}, false},
+		{"clusters_included", &util.GenericPlacementFields{
+			Clusters: []util.GenericClusterReference{{Name: "c0"}, {Name: "c1"}},
+		}, true},
+		{"clusters_not_included", &util.GenericPlacementFields{
+			Clusters: []util.GenericClusterReference{{Name: "c0"}},
+		}, false},
+		{"clusters_0items", &util.GenericPlacementFields{
+			Clusters:        []util.GenericClusterReference{},
+			ClusterSelector: &metav1.LabelSelector{},
+		}, false},
+		{"clusterSelector", &util.GenericPlacementFields{
+			ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"a": "b"}},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := placementMayInclude(tt.placement, "c1"); got != tt.want {
+				t.Errorf("placementMayInclude() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,15 +3,19 @@ package main
 import (
 	"flag"
 	"os"
+	// Embed the time zone database for TariffSchedule as distroless images do not have one.
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
@@ -43,11 +47,15 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableSLPHTTPRoute bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableSLPHTTPRoute, "enable-slp-httproute", true,
+		"Enable the controller that applies ServiceLoadbalancingPreference weights to Gateway API HTTPRoutes. "+
+			"The controller is skipped if Gateway API CRDs are not installed.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -63,6 +71,9 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "1e7b02d1.bitmedia.co.jp",
+		// Secrets and ConfigMaps are read only by some optimizer methods (e.g. to access member clusters),
+		// so do not cache (and watch) all of them in the cluster.
+		ClientDisableCacheFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -91,6 +102,10 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "WAOFedConfig")
 		os.Exit(1)
 	}
+	if err = waofedv1beta1.SetupFederatedObjectWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "FederatedObject")
+		os.Exit(1)
+	}
 	if err = (&controllers.SLPOptimizerReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -98,6 +113,22 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "SLPOptimizer")
 		os.Exit(1)
 	}
+	if err = (&controllers.WAOFedConfigReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WAOFedConfig")
+		os.Exit(1)
+	}
+	if enableSLPHTTPRoute {
+		if err = (&controllers.SLPHTTPRouteReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SLPHTTPRoute")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -11,4 +11,4 @@ cluster0=$PROJECT_NAME-test-0
 
 set +x
 make test
-KUBEBUILDER_ASSETS="$LOCALBIN"/k8s/1.25.0-linux-amd64 go test ./... -coverprofile cover.out -tags=testOnExistingCluster,testOnExistingClusterRSPWAO
+KUBEBUILDER_ASSETS="$LOCALBIN"/k8s/1.25.0-linux-amd64 go test ./... -coverprofile cover.out -tags=testOnExistingCluster,testOnExistingClusterRSPWAO,testOnExistingClusterSLPWAO